@@ -0,0 +1,239 @@
+// Package analysis looks for patterns in performance platform statistics
+// that content designers should know about.
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alphagov/metadata-api/performance_platform"
+)
+
+// Method is how the baseline for a value, and its spread, are measured.
+type Method string
+
+const (
+	// ZScore compares a value with the mean and standard deviation of the
+	// values before it.
+	ZScore Method = "zscore"
+
+	// MAD compares a value with the median of the values before it and
+	// their median absolute deviation, which a few outliers don't skew.
+	MAD Method = "mad"
+)
+
+// defaultThresholds are the scores beyond which a value is anomalous if the
+// threshold isn't given. 3.5 is the usual cut-off for MAD-based scores.
+var defaultThresholds = map[Method]float64{
+	ZScore: 3,
+	MAD:    3.5,
+}
+
+const (
+	defaultWindow = 28
+	maxWindow     = 366
+)
+
+// Options configure anomaly detection. Each value is compared with a
+// baseline of up to Window values before it, and only once there are at
+// least MinBaseline of them. It's anomalous if its score is at least
+// Threshold away from zero. The zero Options is DefaultOptions.
+type Options struct {
+	Method      Method
+	Window      int
+	MinBaseline int
+	Threshold   float64
+}
+
+// DefaultOptions compares each value with the mean of the 28 before it.
+func DefaultOptions() Options {
+	options, _ := ParseOptions("", "", "")
+	return options
+}
+
+// ParseOptions validates the method, threshold and window parameters of a
+// request, any of which may be empty.
+func ParseOptions(method, threshold, window string) (Options, error) {
+	options := Options{Method: Method(method), Window: defaultWindow, MinBaseline: 7}
+
+	if options.Method == "" {
+		options.Method = ZScore
+	}
+	if _, ok := defaultThresholds[options.Method]; !ok {
+		return Options{}, fmt.Errorf("anomaly method must be zscore or mad, not %q", method)
+	}
+
+	options.Threshold = defaultThresholds[options.Method]
+	if threshold != "" {
+		value, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || value <= 0 || math.IsInf(value, 0) {
+			return Options{}, fmt.Errorf("anomaly threshold must be a positive number, not %q", threshold)
+		}
+		options.Threshold = value
+	}
+
+	if window != "" {
+		value, err := strconv.Atoi(window)
+		if err != nil || value < 2 || value > maxWindow {
+			return Options{}, fmt.Errorf("anomaly window must be a number of days from 2 to %d, not %q",
+				maxWindow, window)
+		}
+		options.Window = value
+		if options.MinBaseline > value {
+			options.MinBaseline = value
+		}
+	}
+
+	return options, nil
+}
+
+// String identifies the options, for use in cache keys.
+func (options Options) String() string {
+	return fmt.Sprintf("%s:%d:%d:%g", options.Method, options.Window, options.MinBaseline, options.Threshold)
+}
+
+// Anomaly is a value in Series that's out of line with those before it.
+// Direction is "spike" or "drop".
+type Anomaly struct {
+	Series    string    `json:"series"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     int       `json:"value"`
+	Baseline  float64   `json:"baseline"`
+	Score     float64   `json:"score"`
+	Direction string    `json:"direction"`
+}
+
+// DetectAll looks for anomalies in the page views and problem reports in
+// statistics, which may be nil, returning them in time order.
+func DetectAll(statistics *performance_platform.Statistics, options Options) []Anomaly {
+	if statistics == nil {
+		return nil
+	}
+
+	anomalies := append(
+		Detect("page_views", statistics.PageViews, options),
+		Detect("problem_reports", statistics.ProblemReports, options)...)
+
+	sort.Stable(byTimestamp(anomalies))
+	return anomalies
+}
+
+// Detect looks for anomalies in a series, named series in the results. The
+// values for every path on the same date are added together first.
+func Detect(series string, statistics []performance_platform.Statistic, options Options) []Anomaly {
+	if options.Method == "" {
+		options = DefaultOptions()
+	}
+
+	totals := make(map[time.Time]int)
+	for _, statistic := range statistics {
+		totals[statistic.Timestamp] += statistic.Value
+	}
+
+	timestamps := make([]time.Time, 0, len(totals))
+	for timestamp := range totals {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Sort(timeOrder(timestamps))
+
+	values := make([]float64, len(timestamps))
+	for i, timestamp := range timestamps {
+		values[i] = float64(totals[timestamp])
+	}
+
+	anomalies := []Anomaly{}
+	for i, value := range values {
+		start := i - options.Window
+		if start < 0 {
+			start = 0
+		}
+		baseline := values[start:i]
+		if len(baseline) < options.MinBaseline || len(baseline) == 0 {
+			continue
+		}
+
+		center, spread := options.measure(baseline)
+		score := (value - center) / spread
+		if math.Abs(score) < options.Threshold {
+			continue
+		}
+
+		direction := "spike"
+		if score < 0 {
+			direction = "drop"
+		}
+
+		anomalies = append(anomalies, Anomaly{
+			Series:    series,
+			Timestamp: timestamps[i],
+			Value:     int(value),
+			Baseline:  round(center),
+			Score:     round(score),
+			Direction: direction,
+		})
+	}
+
+	return anomalies
+}
+
+// measure returns the center of baseline and the spread of values around
+// it. Counts vary by about the square root of their mean even when they've
+// been steady so far, so the spread is never taken to be less than that,
+// which stops every change to a flat series from looking anomalous.
+func (options Options) measure(baseline []float64) (center, spread float64) {
+	if options.Method == MAD {
+		center = median(baseline)
+
+		deviations := make([]float64, len(baseline))
+		for i, value := range baseline {
+			deviations[i] = math.Abs(value - center)
+		}
+		// Scaled so that it estimates the standard deviation of normally
+		// distributed values, and scores are comparable with z-scores.
+		spread = 1.4826 * median(deviations)
+	} else {
+		for _, value := range baseline {
+			center += value
+		}
+		center /= float64(len(baseline))
+
+		for _, value := range baseline {
+			spread += (value - center) * (value - center)
+		}
+		spread = math.Sqrt(spread / float64(len(baseline)))
+	}
+
+	return center, math.Max(spread, math.Sqrt(math.Max(center, 1)))
+}
+
+type byTimestamp []Anomaly
+
+func (anomalies byTimestamp) Len() int      { return len(anomalies) }
+func (anomalies byTimestamp) Swap(i, j int) { anomalies[i], anomalies[j] = anomalies[j], anomalies[i] }
+func (anomalies byTimestamp) Less(i, j int) bool {
+	return anomalies[i].Timestamp.Before(anomalies[j].Timestamp)
+}
+
+type timeOrder []time.Time
+
+func (times timeOrder) Len() int           { return len(times) }
+func (times timeOrder) Swap(i, j int)      { times[i], times[j] = times[j], times[i] }
+func (times timeOrder) Less(i, j int) bool { return times[i].Before(times[j]) }
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+func round(value float64) float64 {
+	return math.Floor(value*100+0.5) / 100
+}
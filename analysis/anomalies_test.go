@@ -0,0 +1,141 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	. "github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fixtureStatistics reads the performance section of an /info fixture.
+func fixtureStatistics(name string) *performance_platform.Statistics {
+	body, err := ioutil.ReadFile("../fixtures/" + name)
+	Expect(err).To(BeNil())
+
+	var response struct {
+		Performance *performance_platform.Statistics `json:"performance"`
+	}
+	Expect(json.Unmarshal(body, &response)).To(Succeed())
+	return response.Performance
+}
+
+func daily(values ...int) []performance_platform.Statistic {
+	start := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	series := []performance_platform.Statistic{}
+	for i, value := range values {
+		series = append(series, performance_platform.Statistic{Path: "/foo", Timestamp: start.AddDate(0, 0, i), Value: value})
+	}
+	return series
+}
+
+func day(n int) time.Time {
+	return time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+var _ = Describe("Anomalies", func() {
+	Describe("ParseOptions", func() {
+		It("defaults to z-scores over 28 days", func() {
+			options, err := ParseOptions("", "", "")
+			Expect(err).To(BeNil())
+			Expect(options).To(Equal(Options{Method: ZScore, Window: 28, MinBaseline: 7, Threshold: 3}))
+			Expect(DefaultOptions()).To(Equal(options))
+		})
+
+		It("uses a higher default threshold for MAD", func() {
+			options, err := ParseOptions("mad", "", "")
+			Expect(err).To(BeNil())
+			Expect(options.Threshold).To(Equal(3.5))
+		})
+
+		It("never needs a longer baseline than the window", func() {
+			options, err := ParseOptions("", "2.5", "5")
+			Expect(err).To(BeNil())
+			Expect(options).To(Equal(Options{Method: ZScore, Window: 5, MinBaseline: 5, Threshold: 2.5}))
+		})
+
+		for _, invalid := range [][3]string{
+			{"mean", "", ""}, {"", "-1", ""}, {"", "lots", ""}, {"", "", "1"}, {"", "", "1000"},
+		} {
+			invalid := invalid
+
+			It("rejects "+invalid[0]+" "+invalid[1]+" "+invalid[2], func() {
+				_, err := ParseOptions(invalid[0], invalid[1], invalid[2])
+				Expect(err).ToNot(BeNil())
+			})
+		}
+	})
+
+	Describe("over the fixtures", func() {
+		for _, method := range []Method{ZScore, MAD} {
+			options := Options{Method: method, Window: 28, MinBaseline: 2, Threshold: 3}
+
+			It("flags the jump in problem reports using "+string(method), func() {
+				anomalies := DetectAll(fixtureStatistics("info_response_content_store.json"), options)
+				Expect(anomalies).To(Equal([]Anomaly{{
+					Series:    "problem_reports",
+					Timestamp: time.Date(2014, 7, 27, 0, 0, 0, 0, time.UTC),
+					Value:     16,
+					Baseline:  0,
+					Score:     16,
+					Direction: "spike",
+				}}))
+			})
+
+			It("adds up the paths of a multipart item using "+string(method), func() {
+				anomalies := DetectAll(fixtureStatistics("info_response_multipart.json"), options)
+				Expect(anomalies).To(HaveLen(1))
+				Expect(anomalies[0].Value).To(Equal(32))
+				Expect(anomalies[0].Baseline).To(Equal(0.5))
+				Expect(anomalies[0].Score).To(Equal(31.5))
+			})
+		}
+
+		It("finds nothing without enough history", func() {
+			Expect(DetectAll(fixtureStatistics("info_response_multipart.json"), DefaultOptions())).To(BeEmpty())
+		})
+	})
+
+	Describe("Detect", func() {
+		series := daily(100, 102, 98, 101, 99, 1000, 100, 101, 99, 100, 160, 40)
+
+		It("flags spikes and drops", func() {
+			anomalies := Detect("page_views", daily(100, 102, 98, 101, 99, 40), Options{
+				Method: ZScore, Window: 28, MinBaseline: 3, Threshold: 3,
+			})
+			Expect(anomalies).To(HaveLen(1))
+			Expect(anomalies[0].Timestamp).To(Equal(day(5)))
+			Expect(anomalies[0].Direction).To(Equal("drop"))
+			Expect(anomalies[0].Baseline).To(Equal(100.0))
+		})
+
+		It("lets an outlier hide later anomalies from z-scores", func() {
+			anomalies := Detect("page_views", series, Options{Method: ZScore, Window: 28, MinBaseline: 3, Threshold: 3})
+			Expect(anomalies).To(HaveLen(1))
+			Expect(anomalies[0].Timestamp).To(Equal(day(5)))
+		})
+
+		It("isn't thrown by outliers using MAD", func() {
+			anomalies := Detect("page_views", series, Options{Method: MAD, Window: 28, MinBaseline: 3, Threshold: 3.5})
+			Expect(anomalies).To(HaveLen(3))
+			Expect(anomalies[1].Timestamp).To(Equal(day(10)))
+			Expect(anomalies[1].Direction).To(Equal("spike"))
+			Expect(anomalies[2].Timestamp).To(Equal(day(11)))
+			Expect(anomalies[2].Direction).To(Equal("drop"))
+		})
+
+		It("only compares with the window before each value", func() {
+			anomalies := Detect("page_views", daily(10, 10, 10, 1000, 1000, 1000, 1000), Options{
+				Method: MAD, Window: 3, MinBaseline: 3, Threshold: 3.5,
+			})
+			Expect(anomalies).To(HaveLen(2))
+			Expect(anomalies[0].Timestamp).To(Equal(day(3)))
+			Expect(anomalies[1].Timestamp).To(Equal(day(4)))
+		})
+	})
+})
@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alphagov/metadata-api/performance_platform"
+)
+
+const (
+	// minRecentDays is how far back from the end of the statistics a search
+	// counts as recent, rather than part of the baseline, at least.
+	minRecentDays = 7
+
+	// minEmergingSearches is how many more, or fewer, searches than expected
+	// a term needs before it's reported, so that a handful of searches for
+	// an obscure term don't make it look like it's taking off.
+	minEmergingSearches = 3
+
+	risingRatio  = 1.5
+	fallingRatio = 0.5
+)
+
+// EmergingSearchTerms are the search terms whose popularity has changed
+// recently, which may point to needs the page doesn't meet. Recently is the
+// last 7 days for daily statistics, and otherwise the last whole periods
+// that cover at least 7 days: the last week, or the last month.
+//
+// Rising and Falling have at least half as many searches again, or at most
+// half as many, as the baseline before then suggests. New weren't searched
+// for at all before, and Spikes are the days on which a term was searched
+// for anomalously often, named by keyword in Series.
+type EmergingSearchTerms struct {
+	Rising  []TermTrend `json:"rising"`
+	Falling []TermTrend `json:"falling"`
+	New     []TermTrend `json:"new"`
+	Spikes  []Anomaly   `json:"spikes"`
+}
+
+// TermTrend compares the searches for a term recently with the number
+// expected from the rate before then. PercentageChange is nil for new
+// terms.
+type TermTrend struct {
+	Keyword          string   `json:"keyword"`
+	RecentSearches   int      `json:"recent_searches"`
+	ExpectedSearches float64  `json:"expected_searches"`
+	PercentageChange *float64 `json:"percentage_change"`
+}
+
+// DetectEmergingSearchTerms compares recent searches for each of the terms in
+// statistics.AllSearchTerms with those before, and looks for spikes in them
+// using options. Each list has at most limit terms, the biggest changes
+// first.
+//
+// It returns nil if statistics is nil, is all recent, or there were no
+// searches before then to compare with.
+func DetectEmergingSearchTerms(statistics *performance_platform.Statistics, limit int,
+	options Options) *EmergingSearchTerms {
+	if statistics == nil || statistics.StartAt.IsZero() {
+		return nil
+	}
+
+	recentStart := recentStart(statistics)
+	recentDays := statistics.EndAt.Sub(recentStart).Hours() / 24
+	baselineDays := recentStart.Sub(statistics.StartAt).Hours() / 24
+	if baselineDays <= 0 {
+		return nil
+	}
+
+	terms := statistics.AllSearchTerms
+	if terms == nil {
+		terms = statistics.SearchTerms
+	}
+
+	inWindow := func(timestamp time.Time) bool {
+		return !timestamp.Before(statistics.StartAt) && timestamp.Before(statistics.EndAt)
+	}
+
+	periods := make(map[time.Time]bool)
+	for _, statistic := range statistics.Searches {
+		if inWindow(statistic.Timestamp) {
+			periods[statistic.Timestamp] = true
+		}
+	}
+
+	type counts struct{ recent, baseline int }
+	termCounts := make([]counts, len(terms))
+	totalBaseline := 0
+
+	for i, term := range terms {
+		for _, statistic := range term.Searches {
+			if !inWindow(statistic.Timestamp) {
+				continue
+			}
+			periods[statistic.Timestamp] = true
+
+			if statistic.Timestamp.Before(recentStart) {
+				termCounts[i].baseline += statistic.Value
+			} else {
+				termCounts[i].recent += statistic.Value
+			}
+		}
+		totalBaseline += termCounts[i].baseline
+	}
+
+	if totalBaseline == 0 {
+		return nil
+	}
+
+	emerging := &EmergingSearchTerms{
+		Rising:  []TermTrend{},
+		Falling: []TermTrend{},
+		New:     []TermTrend{},
+		Spikes:  []Anomaly{},
+	}
+
+	for i, term := range terms {
+		recent, expected := termCounts[i].recent, float64(termCounts[i].baseline)*recentDays/baselineDays
+		trend := TermTrend{Keyword: term.Keyword, RecentSearches: recent, ExpectedSearches: round(expected)}
+
+		switch {
+		case termCounts[i].baseline == 0:
+			if recent >= minEmergingSearches {
+				emerging.New = append(emerging.New, trend)
+			}
+		case float64(recent) >= expected*risingRatio && float64(recent)-expected >= minEmergingSearches:
+			change := round(100 * (float64(recent) - expected) / expected)
+			trend.PercentageChange = &change
+			emerging.Rising = append(emerging.Rising, trend)
+		case float64(recent) <= expected*fallingRatio && expected-float64(recent) >= minEmergingSearches:
+			change := round(100 * (float64(recent) - expected) / expected)
+			trend.PercentageChange = &change
+			emerging.Falling = append(emerging.Falling, trend)
+		}
+
+		for _, anomaly := range Detect(term.Keyword, filled(term.Searches, periods), options) {
+			if anomaly.Direction == "spike" && !anomaly.Timestamp.Before(recentStart) &&
+				anomaly.Value >= minEmergingSearches {
+				emerging.Spikes = append(emerging.Spikes, anomaly)
+			}
+		}
+	}
+
+	sort.Sort(byChange(emerging.Rising))
+	sort.Sort(byChange(emerging.Falling))
+	sort.Sort(byChange(emerging.New))
+	sort.Sort(byScore(emerging.Spikes))
+
+	emerging.Rising = limitTrends(emerging.Rising, limit)
+	emerging.Falling = limitTrends(emerging.Falling, limit)
+	emerging.New = limitTrends(emerging.New, limit)
+	if limit > 0 && len(emerging.Spikes) > limit {
+		emerging.Spikes = emerging.Spikes[:limit]
+	}
+
+	return emerging
+}
+
+// recentStart is when searches start counting as recent: the start of as
+// few of the last periods of statistics as cover minRecentDays, so that
+// weekly or monthly values are either recent or not.
+func recentStart(statistics *performance_platform.Statistics) time.Time {
+	start := performance_platform.PeriodStart(statistics.Period, statistics.EndAt.AddDate(0, 0, -1))
+	for statistics.EndAt.Sub(start) < minRecentDays*24*time.Hour {
+		start = performance_platform.PeriodStart(statistics.Period, start.AddDate(0, 0, -1))
+	}
+	return start
+}
+
+// filled is series with a zero for every one of periods it has no value for,
+// as terms that weren't searched for on a day have no value for it.
+func filled(series []performance_platform.Statistic, periods map[time.Time]bool) []performance_platform.Statistic {
+	seen := make(map[time.Time]bool)
+	for _, statistic := range series {
+		seen[statistic.Timestamp] = true
+	}
+
+	result := append([]performance_platform.Statistic(nil), series...)
+	for timestamp := range periods {
+		if !seen[timestamp] {
+			result = append(result, performance_platform.Statistic{Timestamp: timestamp})
+		}
+	}
+	return result
+}
+
+func limitTrends(trends []TermTrend, limit int) []TermTrend {
+	if limit > 0 && len(trends) > limit {
+		return trends[:limit]
+	}
+	return trends
+}
+
+// byChange orders trends by how far their searches are from those expected,
+// the furthest first.
+type byChange []TermTrend
+
+func (trends byChange) Len() int      { return len(trends) }
+func (trends byChange) Swap(i, j int) { trends[i], trends[j] = trends[j], trends[i] }
+func (trends byChange) Less(i, j int) bool {
+	a, b := trends[i].difference(), trends[j].difference()
+	if a == b {
+		return trends[i].Keyword < trends[j].Keyword
+	}
+	return a > b
+}
+
+func (trend TermTrend) difference() float64 {
+	difference := float64(trend.RecentSearches) - trend.ExpectedSearches
+	if difference < 0 {
+		return -difference
+	}
+	return difference
+}
+
+// byScore orders anomalies with the highest scoring first.
+type byScore []Anomaly
+
+func (anomalies byScore) Len() int      { return len(anomalies) }
+func (anomalies byScore) Swap(i, j int) { anomalies[i], anomalies[j] = anomalies[j], anomalies[i] }
+func (anomalies byScore) Less(i, j int) bool {
+	if anomalies[i].Score == anomalies[j].Score {
+		if anomalies[i].Series == anomalies[j].Series {
+			return anomalies[i].Timestamp.Before(anomalies[j].Timestamp)
+		}
+		return anomalies[i].Series < anomalies[j].Series
+	}
+	return anomalies[i].Score > anomalies[j].Score
+}
@@ -0,0 +1,159 @@
+package analysis_test
+
+import (
+	"time"
+
+	. "github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// term is searched for value times a day from day start until day end.
+func term(keyword string, start, end, value int) performance_platform.SearchTerm {
+	searchTerm := performance_platform.SearchTerm{Keyword: keyword}
+	for i := start; i < end; i++ {
+		searchTerm.Searches = append(searchTerm.Searches, performance_platform.Statistic{Timestamp: day(i), Value: value})
+		searchTerm.TotalSearches += value
+	}
+	return searchTerm
+}
+
+func withTerms(terms ...performance_platform.SearchTerm) *performance_platform.Statistics {
+	searchTerms := performance_platform.SearchTerms{}
+	for _, term := range terms {
+		searchTerms = append(searchTerms, term)
+	}
+
+	return &performance_platform.Statistics{
+		SearchTerms: searchTerms,
+		StartAt:     day(0),
+		EndAt:       day(35),
+	}
+}
+
+func join(terms ...performance_platform.SearchTerm) performance_platform.SearchTerm {
+	joined := performance_platform.SearchTerm{Keyword: terms[0].Keyword}
+	for _, term := range terms {
+		joined.Searches = append(joined.Searches, term.Searches...)
+		joined.TotalSearches += term.TotalSearches
+	}
+	return joined
+}
+
+func percentage(value float64) *float64 {
+	return &value
+}
+
+var _ = Describe("DetectEmergingSearchTerms", func() {
+	var statistics *performance_platform.Statistics
+
+	BeforeEach(func() {
+		statistics = withTerms(
+			term("steady", 0, 35, 1),
+			join(term("rising", 0, 28, 1), term("rising", 28, 35, 5)),
+			term("falling", 0, 28, 4),
+			join(term("new", 33, 34, 2), term("new", 34, 35, 3)),
+			term("rare", 34, 35, 1),
+		)
+	})
+
+	It("compares the last 7 days with the rate before", func() {
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+
+		Expect(emerging.Rising).To(Equal([]TermTrend{
+			{Keyword: "rising", RecentSearches: 35, ExpectedSearches: 7, PercentageChange: percentage(400)},
+		}))
+		Expect(emerging.Falling).To(Equal([]TermTrend{
+			{Keyword: "falling", RecentSearches: 0, ExpectedSearches: 28, PercentageChange: percentage(-100)},
+		}))
+	})
+
+	It("reports terms first searched for in the last 7 days", func() {
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+
+		Expect(emerging.New).To(Equal([]TermTrend{{Keyword: "new", RecentSearches: 5}}))
+	})
+
+	It("reports spikes in the last 7 days, the highest scoring first", func() {
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+
+		Expect(emerging.Spikes).ToNot(BeEmpty())
+		Expect(emerging.Spikes[0]).To(Equal(Anomaly{
+			Series: "rising", Timestamp: day(28), Value: 5, Baseline: 1, Score: 4, Direction: "spike",
+		}))
+		for _, spike := range emerging.Spikes {
+			Expect(spike.Series).To(Equal("rising"))
+		}
+	})
+
+	It("limits each list", func() {
+		statistics = withTerms(
+			join(term("a", 0, 28, 1), term("a", 28, 35, 5)),
+			join(term("b", 0, 28, 1), term("b", 28, 35, 6)),
+		)
+
+		emerging := DetectEmergingSearchTerms(statistics, 1, DefaultOptions())
+		Expect(emerging.Rising).To(HaveLen(1))
+		Expect(emerging.Rising[0].Keyword).To(Equal("b"))
+		Expect(emerging.Spikes).To(HaveLen(1))
+		Expect(emerging.Spikes[0].Series).To(Equal("b"))
+	})
+
+	It("prefers every search term to those shown", func() {
+		statistics.AllSearchTerms = statistics.SearchTerms
+		statistics.SearchTerms = statistics.SearchTerms[:1]
+
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+		Expect(emerging.Rising).To(HaveLen(1))
+	})
+
+	It("ignores searches outside the period covered", func() {
+		statistics = withTerms(term("old", -10, 0, 5), term("steady", 0, 35, 1))
+
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+		Expect(emerging.Falling).To(BeEmpty())
+	})
+
+	It("compares the last month of monthly statistics with the months before", func() {
+		month := func(n int) time.Time { return time.Date(2017, time.Month(n), 1, 0, 0, 0, 0, time.UTC) }
+		monthly := func(keyword string, values ...int) performance_platform.SearchTerm {
+			searchTerm := performance_platform.SearchTerm{Keyword: keyword}
+			for i, value := range values {
+				searchTerm.Searches = append(searchTerm.Searches,
+					performance_platform.Statistic{Timestamp: month(i + 1), Value: value})
+			}
+			return searchTerm
+		}
+
+		statistics = &performance_platform.Statistics{
+			SearchTerms: performance_platform.SearchTerms{
+				monthly("steady", 100, 100, 100, 100, 100, 100),
+				monthly("rising", 100, 100, 100, 100, 100, 300),
+			},
+			StartAt: month(1),
+			EndAt:   month(7),
+			Period:  "month",
+		}
+
+		emerging := DetectEmergingSearchTerms(statistics, 10, DefaultOptions())
+		Expect(emerging.Falling).To(BeEmpty())
+		Expect(emerging.Rising).To(HaveLen(1))
+		Expect(emerging.Rising[0].Keyword).To(Equal("rising"))
+		Expect(emerging.Rising[0].RecentSearches).To(Equal(300))
+	})
+
+	It("returns nil without a baseline to compare with", func() {
+		Expect(DetectEmergingSearchTerms(nil, 10, DefaultOptions())).To(BeNil())
+
+		statistics.StartAt = day(28)
+		Expect(DetectEmergingSearchTerms(statistics, 10, DefaultOptions())).To(BeNil())
+
+		statistics = withTerms(term("new", 30, 35, 5))
+		Expect(DetectEmergingSearchTerms(statistics, 10, DefaultOptions())).To(BeNil())
+
+		statistics.StartAt = time.Time{}
+		Expect(DetectEmergingSearchTerms(statistics, 10, DefaultOptions())).To(BeNil())
+	})
+})
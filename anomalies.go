@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alphagov/metadata-api/analysis"
+)
+
+type AnomaliesResponse struct {
+	Anomalies    []analysis.Anomaly `json:"anomalies"`
+	ResponseInfo *ResponseInfo      `json:"_response_info"`
+}
+
+// AnomaliesHandler responds with just the anomalies that /info would, without
+// fetching needs. It takes the same parameters.
+func AnomaliesHandler(fetcher *Fetcher) func(http.ResponseWriter, *http.Request) {
+	renderAnomaliesError := func(w http.ResponseWriter, status int, errorString string) {
+		renderer.JSON(w, status, &AnomaliesResponse{ResponseInfo: &ResponseInfo{Status: errorString}})
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		slug := requestedSlug(r, "/anomalies")
+
+		options, err := ParseInfoOptions(r.URL.Query())
+		if err != nil {
+			renderAnomaliesError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		anomalies, err := fetcher.Anomalies(r.Context(), slug, options)
+		if err != nil {
+			metadataErr := err.(*MetadataError)
+			if metadataErr.Location != "" {
+				w.Header().Set("Location", redirectLocation(r, "/anomalies", metadataErr.Location))
+			}
+			renderer.JSON(w, metadataErr.Status, &AnomaliesResponse{ResponseInfo: metadataErr.metadata().ResponseInfo})
+			return
+		}
+
+		renderer.JSON(w, http.StatusOK, &AnomaliesResponse{
+			Anomalies:    anomalies,
+			ResponseInfo: &ResponseInfo{Status: "ok"},
+		})
+	}
+}
+
+// Anomalies looks for anomalies in the statistics for slug. Any error is a
+// *MetadataError.
+func (fetcher *Fetcher) Anomalies(ctx context.Context, slug string, options InfoOptions) ([]analysis.Anomaly, error) {
+	slug, err := normaliseSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if slug == "/" {
+		return nil, &MetadataError{Status: http.StatusNotFound, Message: "not found"}
+	}
+
+	ctx, cancel := withTimeout(ctx, fetcher.config.RequestTimeout)
+	defer cancel()
+
+	artefact, slug, _, err := fetcher.followArtefact(ctx, slug, options.FollowRedirects)
+	if err != nil {
+		return nil, err
+	}
+	artefact, slug, err = fetcher.translation(ctx, artefact, slug, options.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	performanceCtx, cancelPerformance := withTimeout(ctx, fetcher.config.PerformanceAPITimeout)
+	defer cancelPerformance()
+
+	performance, err := fetcher.artefactPerformance(performanceCtx, slug, artefact, options)
+	if err != nil {
+		status, message := newSectionError(PerformanceSection, performanceCtx, err).strictError()
+		return nil, &MetadataError{Status: status, Message: message}
+	}
+
+	return analysis.DetectAll(performance, options.Anomalies), nil
+}
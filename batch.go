@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alphagov/metadata-api/errgroup"
+	"github.com/alphagov/metadata-api/need_api"
+)
+
+// maxBatchBodyBytes bounds the body of a batch request.
+const maxBatchBodyBytes = 1 << 20
+
+// BatchInfoHandler answers a POST of a JSON list of paths with a map of each
+// path to what /info would respond with for it. A GET is handled as an /info
+// request, in case there's content at /batch. Like /info, it's deprecated in
+// favour of the versioned paths.
+func BatchInfoHandler(fetcher *Fetcher) func(http.ResponseWriter, *http.Request) {
+	infoHandler := FetcherInfoHandler(fetcher)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			infoHandler(w, r)
+			return
+		}
+
+		version := negotiateVersion(r)
+		deprecate(w, r, version)
+		serveBatch(w, r, fetcher, version)
+	}
+}
+
+// VersionedBatchInfoHandler is BatchInfoHandler in version's schema under
+// its prefix, such as /v2/info/batch.
+func VersionedBatchInfoHandler(fetcher *Fetcher, version Version) func(http.ResponseWriter, *http.Request) {
+	infoHandler := VersionedInfoHandler(fetcher, version)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			infoHandler(w, r)
+			return
+		}
+
+		serveBatch(w, r, fetcher, version)
+	}
+}
+
+func serveBatch(w http.ResponseWriter, r *http.Request, fetcher *Fetcher, version Version) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		renderVersionError(w, version, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	options, err := ParseInfoOptions(r.URL.Query())
+	if err != nil {
+		renderVersionError(w, version, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	slugs, err := decodePaths(w, r)
+	if err != nil {
+		renderVersionError(w, version, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if max := fetcher.config.BatchMaxPaths; len(slugs) > max {
+		renderVersionError(w, version, http.StatusBadRequest, fmt.Sprintf("at most %d paths can be fetched at once", max))
+		return
+	}
+
+	results := make(map[string]interface{}, len(slugs))
+	for slug, metadata := range fetcher.Batch(r.Context(), slugs, options) {
+		results[slug] = version.body(metadata)
+	}
+
+	body, err := json.Marshal(results)
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", version.contentType())
+	renderer.Data(w, http.StatusOK, body)
+}
+
+// Batch returns what Info would for each of slugs, with any error as the
+// body of the error response.
+func (fetcher *Fetcher) Batch(ctx context.Context, slugs []string, options InfoOptions) map[string]*Metadata {
+	results := make(map[string]*Metadata, len(slugs))
+	fetcher.each(ctx, slugs, options, func(slug string, metadata *Metadata) {
+		results[slug] = metadata
+	})
+	return results
+}
+
+// each calls found with what Info would return for each distinct slug, with
+// any error as the body of the error response and its status, as soon as
+// it's ready. Calls
+// to found are never concurrent. Each need is fetched once for all of the
+// slugs, and the config's concurrency limits apply to them as a whole
+// rather than to each slug. Once ctx is done, no more slugs are started.
+func (fetcher *Fetcher) each(ctx context.Context, slugs []string, options InfoOptions,
+	found func(slug string, metadata *Metadata)) {
+	var (
+		seen  = make(map[string]bool, len(slugs))
+		mutex sync.Mutex
+		needs = newBatchNeeds(ctx, fetcher.need, fetcher.config.NeedAPIConcurrency,
+			fetcher.config.NeedAPITimeout)
+	)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(fetcher.config.BatchConcurrency)
+
+	for _, slug := range slugs {
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		slug := slug
+		group.Go(func() error {
+			metadata, err := fetcher.info(groupCtx, slug, options, needs.fetch)
+			if err != nil {
+				metadataErr := err.(*MetadataError)
+				metadata = metadataErr.metadata()
+				metadata.ResponseInfo.StatusCode = metadataErr.Status
+			}
+
+			mutex.Lock()
+			found(slug, metadata)
+			mutex.Unlock()
+			return nil
+		})
+	}
+
+	group.Wait()
+}
+
+// decodePaths reads a JSON list of paths from the body of r.
+func decodePaths(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	var paths []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&paths); err != nil {
+		return nil, errors.New("expected a JSON list of paths: " + err.Error())
+	}
+	return paths, nil
+}
+
+// batchNeeds remembers each need fetched for a batch, so that it's only
+// fetched once however many slugs share it, and limits how many are fetched
+// at once. Each need is fetched on the batch's context, bounded by timeout,
+// rather than on that of the slug that asked for it first, so that slug
+// giving up doesn't fail it for the others.
+type batchNeeds struct {
+	ctx       context.Context
+	timeout   time.Duration
+	fetchNeed need_api.NeedFetcher
+	slots     chan struct{}
+
+	mutex sync.Mutex
+	needs map[string]*batchNeed
+}
+
+type batchNeed struct {
+	done chan struct{}
+	need *need_api.Need
+	err  error
+}
+
+// newBatchNeeds returns a batchNeeds fetching up to concurrency needs at
+// once, or any number if concurrency is zero or less.
+func newBatchNeeds(ctx context.Context, fetchNeed need_api.NeedFetcher, concurrency int,
+	timeout time.Duration) *batchNeeds {
+	b := &batchNeeds{
+		ctx:       ctx,
+		timeout:   timeout,
+		fetchNeed: fetchNeed,
+		needs:     make(map[string]*batchNeed),
+	}
+	if concurrency > 0 {
+		b.slots = make(chan struct{}, concurrency)
+	}
+	return b
+}
+
+func (b *batchNeeds) fetch(ctx context.Context, id string) (*need_api.Need, error) {
+	b.mutex.Lock()
+	existing, ok := b.needs[id]
+	if !ok {
+		existing = &batchNeed{done: make(chan struct{})}
+		b.needs[id] = existing
+		go b.run(id, existing)
+	}
+	b.mutex.Unlock()
+
+	select {
+	case <-existing.done:
+		return existing.need, existing.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *batchNeeds) run(id string, need *batchNeed) {
+	ctx, cancel := withTimeout(b.ctx, b.timeout)
+	defer cancel()
+
+	need.need, need.err = b.fetchLimited(ctx, id)
+	close(need.done)
+}
+
+func (b *batchNeeds) fetchLimited(ctx context.Context, id string) (*need_api.Need, error) {
+	if b.slots == nil {
+		return b.fetchNeed(ctx, id)
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-b.slots }()
+
+	return b.fetchNeed(ctx, id)
+}
@@ -0,0 +1,190 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/alphagov/metadata-api"
+	"github.com/alphagov/metadata-api/content"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pathJSONRequest is a stubbed content store holding an item for each path.
+type pathJSONRequest map[string]string
+
+func (items pathJSONRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	for path, item := range items {
+		if strings.HasSuffix(url, "/content"+path) {
+			return item, nil
+		}
+	}
+	return "", content.StatusError{StatusCode: http.StatusNotFound}
+}
+
+// delayedJSONRequest is pathJSONRequest, slower for the paths in Delays.
+type delayedJSONRequest struct {
+	pathJSONRequest
+	Delays map[string]time.Duration
+}
+
+func (items delayedJSONRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	for path, delay := range items.Delays {
+		if strings.HasSuffix(url, "/content"+path) {
+			time.Sleep(delay)
+		}
+	}
+	return items.pathJSONRequest.GetJSON(ctx, url, bearerToken)
+}
+
+func contentItem(path string, needIDs ...string) string {
+	ids, _ := json.Marshal(needIDs)
+	return fmt.Sprintf(`{"base_path": %q, "content_id": "id", "title": "Title", "document_type": "answer", "need_ids": %s}`,
+		path, ids)
+}
+
+var _ = Describe("Batch", func() {
+	var (
+		batchServer, needAPI, performanceAPI *httptest.Server
+
+		mutex               sync.Mutex
+		needRequests        map[string]int
+		running, maxRunning int
+		config              *Config
+	)
+
+	postPaths := func(body string) (*http.Response, map[string]map[string]interface{}) {
+		response, err := http.Post(batchServer.URL+"/info/batch", "application/json", strings.NewReader(body))
+		Expect(err).To(BeNil())
+
+		var results map[string]map[string]interface{}
+		responseBody, _ := readResponseBody(response)
+		json.Unmarshal([]byte(responseBody), &results)
+		return response, results
+	}
+
+	BeforeEach(func() {
+		needRequests = make(map[string]int)
+		running, maxRunning = 0, 0
+
+		needAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.URL.Path, "/needs/")
+
+			mutex.Lock()
+			needRequests[id]++
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+
+			fmt.Fprintf(w, `{"_response_info": {"status": "ok"}, "id": %s}`, id)
+		})
+
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":[]}`)
+		})
+
+		config = &Config{NeedAPIConcurrency: 1, BatchMaxPaths: 3, BatchConcurrency: 3}
+		fetcher := NewFetcher(needAPI.URL, performanceAPI.URL, pathJSONRequest{
+			"/one":   contentItem("/one", "100001", "100002"),
+			"/two":   contentItem("/two", "100002", "100003"),
+			"/batch": contentItem("/batch"),
+		}, config)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/info/", FetcherInfoHandler(fetcher))
+		mux.HandleFunc("/info/batch", BatchInfoHandler(fetcher))
+		batchServer = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		batchServer.Close()
+		needAPI.Close()
+		performanceAPI.Close()
+	})
+
+	It("returns metadata or an error for each path", func() {
+		response, results := postPaths(`["/one", "/missing", ""]`)
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(results).To(HaveLen(3))
+
+		Expect(results["/one"]["_response_info"]).To(Equal(map[string]interface{}{"status": "ok"}))
+		Expect(results["/one"]["needs"]).To(HaveLen(2))
+		Expect(results["/missing"]["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "not found", "status_code": 404.0,
+		}))
+		Expect(results[""]["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "not found", "status_code": 404.0,
+		}))
+	})
+
+	It("fetches needs shared by several paths once, within one concurrency limit", func() {
+		_, results := postPaths(`["/one", "/two", "/one"]`)
+		Expect(results["/two"]["needs"]).To(HaveLen(2))
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(needRequests).To(Equal(map[string]int{"100001": 1, "100002": 1, "100003": 1}))
+		Expect(maxRunning).To(Equal(1))
+	})
+
+	It("doesn't fail a shared need for every path when one path gives up on it", func() {
+		needAPI.Close()
+		needAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.URL.Path, "/needs/")
+			if id == "404" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			fmt.Fprintf(w, `{"id": %s}`, id)
+		})
+
+		// /one starts fetching the shared need, then gives up on it when
+		// its other need isn't found.
+		fetcher := NewFetcher(needAPI.URL, performanceAPI.URL, delayedJSONRequest{pathJSONRequest{
+			"/one": contentItem("/one", "100002", "404"),
+			"/two": contentItem("/two", "100002"),
+		}, map[string]time.Duration{"/two": 10 * time.Millisecond}},
+			&Config{NeedAPIConcurrency: 2, BatchMaxPaths: 3, BatchConcurrency: 3})
+		results := fetcher.Batch(context.Background(), []string{"/one", "/two"}, InfoOptions{})
+
+		Expect(results["/one"].SectionOK(NeedsSection)).To(BeFalse())
+		Expect(results["/two"].Errors).To(BeEmpty())
+		Expect(results["/two"].Needs).To(HaveLen(1))
+	})
+
+	It("rejects bodies that aren't a list of paths", func() {
+		response, _ := postPaths(`{"paths": ["/one"]}`)
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects batches with too many paths", func() {
+		response, _ := postPaths(`["/a", "/b", "/c", "/d"]`)
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("still serves content at /batch", func() {
+		response, err := http.Get(batchServer.URL + "/info/batch")
+		Expect(err).To(BeNil())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		body, _ := readResponseBody(response)
+		Expect(body).To(ContainSubstring(`"title":"Title"`))
+	})
+})
@@ -0,0 +1,243 @@
+// Package cache is a small in-process LRU cache whose entries expire after a
+// fixed TTL. Concurrent misses for the same key share a single fetch.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter receives a count for every hit, miss and coalesced lookup. It's
+// satisfied by *statsd.StatsdClient.
+type Counter interface {
+	Incr(stat string, count int64) error
+}
+
+type Cache struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+	counter    Counter
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	calls   map[string]*call
+
+	// Timeout bounds each fetch made by Fetch. Zero leaves it unbounded.
+	Timeout time.Duration
+
+	// Now is used to timestamp entries, and can be replaced in tests.
+	Now func() time.Time
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type call struct {
+	done    chan struct{}
+	value   interface{}
+	expires time.Time
+	err     error
+
+	// waiters is how many callers are still waiting for the fetch, which
+	// is cancelled when the last one gives up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// New returns a Cache holding up to maxEntries values for ttl each. Counts are
+// sent to counter as "cache.<name>.hit", "cache.<name>.miss" and
+// "cache.<name>.coalesced". A ttl of zero or less disables caching.
+func New(name string, ttl time.Duration, maxEntries int, counter Counter) *Cache {
+	return &Cache{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		counter:    counter,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		calls:      make(map[string]*call),
+		Now:        time.Now,
+	}
+}
+
+// Fetch returns the value cached under key, or calls fetch to get it. Errors
+// aren't cached. While fetch is running, other callers asking for the same
+// key wait for its result instead of calling fetch themselves. fetch runs on
+// a context of its own, bounded by Timeout rather than by any one caller, so
+// that each caller only gives up, when its ctx is done, on its own behalf.
+// Once every caller has given up, fetch's context is cancelled.
+func (c *Cache) Fetch(ctx context.Context, key string,
+	fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	value, _, err := c.FetchExpiring(ctx, key, fetch)
+	return value, err
+}
+
+// FetchExpiring is Fetch, also returning when the value expires from the
+// cache. That's now if caching is disabled.
+func (c *Cache) FetchExpiring(ctx context.Context, key string,
+	fetch func(context.Context) (interface{}, error)) (interface{}, time.Time, error) {
+	if c.ttl <= 0 {
+		value, err := fetch(ctx)
+		return value, c.Now(), err
+	}
+
+	c.mutex.Lock()
+	if cached, ok := c.get(key); ok {
+		value, expires := cached.value, cached.expires
+		c.mutex.Unlock()
+		c.count("hit")
+		return value, expires, nil
+	}
+
+	current, coalesced := c.calls[key]
+	if !coalesced {
+		fetchCtx, cancel := context.WithCancel(context.Background())
+		if c.Timeout > 0 {
+			fetchCtx, cancel = context.WithTimeout(context.Background(), c.Timeout)
+		}
+		current = &call{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = current
+		go c.run(fetchCtx, key, current, fetch)
+	}
+	current.waiters++
+	c.mutex.Unlock()
+
+	if coalesced {
+		c.count("coalesced")
+	} else {
+		c.count("miss")
+	}
+
+	select {
+	case <-current.done:
+		return current.value, current.expires, current.err
+	case <-ctx.Done():
+		c.leave(key, current)
+		return nil, time.Time{}, ctx.Err()
+	}
+}
+
+// leave stops a caller waiting for current, cancelling it if nobody else is.
+// Callers that come later start a fetch of their own.
+func (c *Cache) leave(key string, current *call) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	current.waiters--
+	if current.waiters == 0 {
+		current.cancel()
+		if c.calls[key] == current {
+			delete(c.calls, key)
+		}
+	}
+}
+
+// run makes the call to fetch shared by every caller of Fetch for key.
+func (c *Cache) run(ctx context.Context, key string, current *call,
+	fetch func(context.Context) (interface{}, error)) {
+	defer current.cancel()
+
+	current.value, current.err = fetch(ctx)
+
+	c.mutex.Lock()
+	if c.calls[key] == current {
+		delete(c.calls, key)
+	}
+	if current.err == nil {
+		current.expires = c.set(key, current.value)
+	}
+	c.mutex.Unlock()
+	close(current.done)
+}
+
+// Get returns the value cached under key, if it hasn't expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	var value interface{}
+	cached, ok := c.get(key)
+	if ok {
+		value = cached.value
+	}
+	c.mutex.Unlock()
+
+	if ok {
+		c.count("hit")
+	} else {
+		c.count("miss")
+	}
+	return value, ok
+}
+
+// Set caches value under key, replacing anything already there.
+func (c *Cache) Set(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	c.set(key, value)
+	c.mutex.Unlock()
+}
+
+// Len returns the number of entries held, including any that have expired
+// but not yet been evicted.
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache) get(key string) (*entry, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	cached := element.Value.(*entry)
+	if !c.Now().Before(cached.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return cached, true
+}
+
+// set caches value under key, returning when it expires.
+func (c *Cache) set(key string, value interface{}) time.Time {
+	expires := c.Now().Add(c.ttl)
+
+	if element, ok := c.entries[key]; ok {
+		cached := element.Value.(*entry)
+		cached.value, cached.expires = value, expires
+		c.order.MoveToFront(element)
+		return expires
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expires: expires})
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+	return expires
+}
+
+func (c *Cache) count(event string) {
+	if c.counter != nil {
+		c.counter.Incr("cache."+c.name+"."+event, 1)
+	}
+}
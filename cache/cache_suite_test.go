@@ -1,4 +1,4 @@
-package request_test
+package cache_test
 
 import (
 	. "github.com/onsi/ginkgo"
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestRequest(t *testing.T) {
+func TestCache(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Request Suite")
+	RunSpecs(t, "Cache Suite")
 }
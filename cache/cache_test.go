@@ -0,0 +1,269 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/alphagov/metadata-api/cache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type stubCounter struct {
+	mutex  sync.Mutex
+	counts map[string]int64
+}
+
+func (counter *stubCounter) Incr(stat string, count int64) error {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.counts[stat] += count
+	return nil
+}
+
+var _ = Describe("Cache", func() {
+	var (
+		cache   *Cache
+		counter *stubCounter
+		now     time.Time
+		calls   int32
+	)
+
+	fetchValue := func(value string) func(context.Context) (interface{}, error) {
+		return func(context.Context) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return value, nil
+		}
+	}
+
+	BeforeEach(func() {
+		counter = &stubCounter{counts: make(map[string]int64)}
+		cache = New("things", time.Minute, 2, counter)
+		now = time.Date(2017, 4, 1, 12, 0, 0, 0, time.UTC)
+		cache.Now = func() time.Time { return now }
+		calls = 0
+	})
+
+	It("only fetches a value once within its TTL", func() {
+		value, err := cache.Fetch(context.Background(), "a", fetchValue("first"))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("first"))
+
+		value, err = cache.Fetch(context.Background(), "a", fetchValue("second"))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("first"))
+
+		Expect(calls).To(Equal(int32(1)))
+		Expect(counter.counts).To(Equal(map[string]int64{
+			"cache.things.miss": 1,
+			"cache.things.hit":  1,
+		}))
+	})
+
+	It("fetches the value again once it has expired", func() {
+		cache.Fetch(context.Background(), "a", fetchValue("first"))
+		now = now.Add(time.Minute)
+
+		value, _ := cache.Fetch(context.Background(), "a", fetchValue("second"))
+		Expect(value).To(Equal("second"))
+		Expect(calls).To(Equal(int32(2)))
+	})
+
+	It("evicts the least recently used entry when full", func() {
+		cache.Fetch(context.Background(), "a", fetchValue("a"))
+		cache.Fetch(context.Background(), "b", fetchValue("b"))
+		cache.Fetch(context.Background(), "a", fetchValue("a"))
+		cache.Fetch(context.Background(), "c", fetchValue("c"))
+		Expect(cache.Len()).To(Equal(2))
+
+		cache.Fetch(context.Background(), "a", fetchValue("a"))
+		Expect(calls).To(Equal(int32(3)))
+
+		cache.Fetch(context.Background(), "b", fetchValue("b"))
+		Expect(calls).To(Equal(int32(4)))
+	})
+
+	It("doesn't cache errors", func() {
+		_, err := cache.Fetch(context.Background(), "a", func(context.Context) (interface{}, error) {
+			return nil, errors.New("failed")
+		})
+		Expect(err).ToNot(BeNil())
+
+		value, err := cache.Fetch(context.Background(), "a", fetchValue("ok"))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("ok"))
+	})
+
+	It("makes one call for concurrent misses of the same key", func() {
+		release := make(chan struct{})
+		var waitGroup sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			waitGroup.Add(1)
+			go func() {
+				defer waitGroup.Done()
+				value, err := cache.Fetch(context.Background(), "a", func(context.Context) (interface{}, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return "shared", nil
+				})
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("shared"))
+			}()
+		}
+
+		Eventually(func() int64 {
+			counter.mutex.Lock()
+			defer counter.mutex.Unlock()
+			return counter.counts["cache.things.coalesced"]
+		}).Should(Equal(int64(9)))
+		close(release)
+		waitGroup.Wait()
+
+		Expect(calls).To(Equal(int32(1)))
+	})
+
+	It("stops waiting on a shared fetch when the context is done", func() {
+		release, finished := make(chan struct{}), make(chan struct{})
+		defer func() {
+			close(release)
+			<-finished
+		}()
+
+		go func() {
+			defer close(finished)
+			cache.Fetch(context.Background(), "a", func(context.Context) (interface{}, error) {
+				<-release
+				return "slow", nil
+			})
+		}()
+		Eventually(func() int64 {
+			counter.mutex.Lock()
+			defer counter.mutex.Unlock()
+			return counter.counts["cache.things.miss"]
+		}).Should(Equal(int64(1)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := cache.Fetch(ctx, "a", fetchValue("unused"))
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("keeps fetching for other callers when the first one gives up", func() {
+		release := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+
+		first := make(chan error)
+		go func() {
+			_, err := cache.Fetch(ctx, "a", func(fetchCtx context.Context) (interface{}, error) {
+				select {
+				case <-release:
+					return "shared", nil
+				case <-fetchCtx.Done():
+					return nil, fetchCtx.Err()
+				}
+			})
+			first <- err
+		}()
+		Eventually(func() int64 {
+			counter.mutex.Lock()
+			defer counter.mutex.Unlock()
+			return counter.counts["cache.things.miss"]
+		}).Should(Equal(int64(1)))
+
+		second := make(chan interface{})
+		go func() {
+			value, err := cache.Fetch(context.Background(), "a", fetchValue("unused"))
+			Expect(err).To(BeNil())
+			second <- value
+		}()
+		Eventually(func() int64 {
+			counter.mutex.Lock()
+			defer counter.mutex.Unlock()
+			return counter.counts["cache.things.coalesced"]
+		}).Should(Equal(int64(1)))
+
+		cancel()
+		Expect(<-first).To(Equal(context.Canceled))
+
+		close(release)
+		Expect(<-second).To(Equal("shared"))
+		value, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("shared"))
+	})
+
+	It("cancels the fetch once every caller has given up", func() {
+		fetchErr := make(chan error, 1)
+		fetch := func(fetchCtx context.Context) (interface{}, error) {
+			<-fetchCtx.Done()
+			fetchErr <- fetchCtx.Err()
+			return nil, fetchCtx.Err()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		otherCtx, cancelOther := context.WithCancel(context.Background())
+		done := make(chan error, 2)
+		go func() {
+			_, err := cache.Fetch(ctx, "a", fetch)
+			done <- err
+		}()
+		go func() {
+			_, err := cache.Fetch(otherCtx, "a", fetch)
+			done <- err
+		}()
+		Eventually(func() int64 {
+			counter.mutex.Lock()
+			defer counter.mutex.Unlock()
+			return counter.counts["cache.things.miss"] + counter.counts["cache.things.coalesced"]
+		}).Should(Equal(int64(2)))
+
+		cancel()
+		Expect(<-done).To(Equal(context.Canceled))
+		Consistently(fetchErr).ShouldNot(Receive())
+
+		cancelOther()
+		Expect(<-done).To(Equal(context.Canceled))
+		Eventually(fetchErr).Should(Receive(Equal(context.Canceled)))
+
+		value, err := cache.Fetch(context.Background(), "a", fetchValue("again"))
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("again"))
+	})
+
+	It("bounds each fetch by the Timeout", func() {
+		cache.Timeout = 10 * time.Millisecond
+		_, err := cache.Fetch(context.Background(), "a", func(fetchCtx context.Context) (interface{}, error) {
+			<-fetchCtx.Done()
+			return nil, fetchCtx.Err()
+		})
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	It("can be read and written directly", func() {
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeFalse())
+
+		cache.Set("a", "direct")
+		value, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("direct"))
+
+		now = now.Add(time.Minute)
+		_, ok = cache.Get("a")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("passes straight through when the TTL is zero", func() {
+		cache = New("things", 0, 2, counter)
+		cache.Fetch(context.Background(), "a", fetchValue("a"))
+		cache.Fetch(context.Background(), "a", fetchValue("a"))
+
+		Expect(calls).To(Equal(int32(2)))
+		Expect(counter.counts).To(BeEmpty())
+	})
+})
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+// renderMetadata responds with metadata in version's schema along with
+// validators and caching headers, or with 304 Not Modified if the client's
+// copy is still current.
+func renderMetadata(w http.ResponseWriter, r *http.Request, fetcher *Fetcher, metadata *Metadata,
+	version Version) {
+	body, err := json.Marshal(version.body(metadata))
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(body))
+	lastModified := metadata.LastModified()
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(fetcher.freshness(metadata).Seconds())))
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", version.contentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// freshness is how long metadata can be reused for: until the first of the
+// cached values it was made from expires. Statistics change at the start of
+// each day, UTC, so they're never fresh beyond it. Partial and stale
+// responses are not fresh, so that clients pick up the complete response as
+// soon as there is one.
+func (fetcher *Fetcher) freshness(metadata *Metadata) time.Duration {
+	if metadata.ResponseInfo.Status != "ok" || metadata.ResponseInfo.Stale {
+		return 0
+	}
+
+	currentTime := time.Now()
+	freshness := metadata.expires.Sub(currentTime)
+
+	if metadata.Performance != nil {
+		untilTomorrow := now.New(currentTime.UTC()).EndOfDay().Sub(currentTime)
+		if untilTomorrow < freshness {
+			freshness = untilTomorrow
+		}
+	}
+
+	if freshness < 0 {
+		return 0
+	}
+	return freshness
+}
+
+// expiryKey is the context key for the cacheExpiry of the Metadata being
+// fetched.
+type expiryKey struct{}
+
+// cacheExpiry tracks the earliest that any cached value used for some
+// Metadata expires.
+type cacheExpiry struct {
+	mutex   sync.Mutex
+	expires time.Time
+}
+
+// withCacheExpiry is ctx with a new cacheExpiry, to which noteExpiry adds.
+func withCacheExpiry(ctx context.Context) (context.Context, *cacheExpiry) {
+	expiry := &cacheExpiry{}
+	return context.WithValue(ctx, expiryKey{}, expiry), expiry
+}
+
+// noteExpiry records that a value used with ctx expires at expires.
+func noteExpiry(ctx context.Context, expires time.Time) {
+	expiry, ok := ctx.Value(expiryKey{}).(*cacheExpiry)
+	if !ok {
+		return
+	}
+
+	expiry.mutex.Lock()
+	if expiry.expires.IsZero() || expires.Before(expiry.expires) {
+		expiry.expires = expires
+	}
+	expiry.mutex.Unlock()
+}
+
+func (expiry *cacheExpiry) earliest() time.Time {
+	expiry.mutex.Lock()
+	defer expiry.mutex.Unlock()
+	return expiry.expires
+}
+
+// notModified reports whether a conditional GET or HEAD can be answered with
+// 304 Not Modified. As in RFC 7232, If-Modified-Since is ignored when
+// If-None-Match is present.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+
+	// HTTP dates have a resolution of one second.
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison that RFC 7232 specifies for it.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -2,14 +2,96 @@ package main
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	BearerTokenNeedAPI    string
+	BearerTokenNeedAPI string
+
+	// RequestTimeout is the overall deadline for answering an /info request.
+	// The per-upstream timeouts below apply within it.
+	RequestTimeout        time.Duration
+	ContentStoreTimeout   time.Duration
+	NeedAPITimeout        time.Duration
+	PerformanceAPITimeout time.Duration
+
+	// NeedAPIConcurrency is the most needs fetched at once for one request,
+	// and ContentStoreConcurrency the most linked items.
+	NeedAPIConcurrency      int
+	ContentStoreConcurrency int
+
+	// Upstream responses are cached for these durations, in caches holding
+	// up to CacheSize entries each. A zero duration disables that cache.
+	ArtefactCacheTTL   time.Duration
+	NeedCacheTTL       time.Duration
+	StatisticsCacheTTL time.Duration
+	CacheSize          int
+
+	// StaleTTL is how long the last complete response for a slug may be
+	// served in place of one missing data because an upstream failed.
+	StaleTTL time.Duration
+
+	// StaleRefreshInterval is how long after a failed fetch the stale
+	// response for it is refreshed in the background, which is also the
+	// shortest time between refreshes of the same response.
+	StaleRefreshInterval time.Duration
+
+	// A POST to /info/batch may ask for up to BatchMaxPaths paths, of which
+	// BatchConcurrency are fetched at once.
+	BatchMaxPaths    int
+	BatchConcurrency int
 }
 
 func InitConfig() *Config {
 	return &Config{
-		BearerTokenNeedAPI:    os.Getenv("NEED_API_BEARER_TOKEN"),
+		BearerTokenNeedAPI:      os.Getenv("NEED_API_BEARER_TOKEN"),
+		RequestTimeout:          getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		ContentStoreTimeout:     getEnvDuration("CONTENT_STORE_TIMEOUT", 3*time.Second),
+		NeedAPITimeout:          getEnvDuration("NEED_API_TIMEOUT", 3*time.Second),
+		PerformanceAPITimeout:   getEnvDuration("PERFORMANCE_API_TIMEOUT", 6*time.Second),
+		NeedAPIConcurrency:      getEnvInt("NEED_API_CONCURRENCY", 4),
+		ContentStoreConcurrency: getEnvInt("CONTENT_STORE_CONCURRENCY", 4),
+		ArtefactCacheTTL:        getEnvTTL("ARTEFACT_CACHE_TTL", 5*time.Minute),
+		NeedCacheTTL:            getEnvTTL("NEED_CACHE_TTL", time.Hour),
+		StatisticsCacheTTL:      getEnvTTL("STATISTICS_CACHE_TTL", time.Hour),
+		CacheSize:               getEnvInt("CACHE_SIZE", 1000),
+		StaleTTL:                getEnvTTL("STALE_TTL", 24*time.Hour),
+		StaleRefreshInterval:    getEnvDuration("STALE_REFRESH_INTERVAL", 30*time.Second),
+		BatchMaxPaths:           getEnvInt("BATCH_MAX_PATHS", 100),
+		BatchConcurrency:        getEnvInt("BATCH_CONCURRENCY", 8),
+	}
+}
+
+// getEnvDuration parses key as a positive time.Duration such as "500ms",
+// falling back to defaultVal if it's unset or invalid.
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration <= 0 {
+		return defaultVal
 	}
+
+	return duration
+}
+
+// getEnvTTL is getEnvDuration for a cache TTL, which may also be zero to
+// disable that cache.
+func getEnvTTL(key string, defaultVal time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration < 0 {
+		return defaultVal
+	}
+
+	return duration
+}
+
+// getEnvInt parses key as a positive integer, falling back to defaultVal if
+// it's unset or invalid.
+func getEnvInt(key string, defaultVal int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || val <= 0 {
+		return defaultVal
+	}
+
+	return val
 }
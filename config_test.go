@@ -2,6 +2,7 @@ package main_test
 
 import (
 	"os"
+	"time"
 
 	. "github.com/alphagov/metadata-api"
 
@@ -16,10 +17,55 @@ var _ = Describe("Config", func() {
 
 			config := InitConfig()
 			Expect(config).To(Equal(&Config{
-				BearerTokenNeedAPI:    "bar",
+				BearerTokenNeedAPI:      "bar",
+				RequestTimeout:          10 * time.Second,
+				ContentStoreTimeout:     3 * time.Second,
+				NeedAPITimeout:          3 * time.Second,
+				PerformanceAPITimeout:   6 * time.Second,
+				NeedAPIConcurrency:      4,
+				ContentStoreConcurrency: 4,
+				ArtefactCacheTTL:        5 * time.Minute,
+				NeedCacheTTL:            time.Hour,
+				StatisticsCacheTTL:      time.Hour,
+				CacheSize:               1000,
+				StaleTTL:                24 * time.Hour,
+				StaleRefreshInterval:    30 * time.Second,
+				BatchMaxPaths:           100,
+				BatchConcurrency:        8,
 			}))
 
 			os.Unsetenv("NEED_API_BEARER_TOKEN")
 		})
+
+		It("reads the need API concurrency as a positive integer", func() {
+			os.Setenv("NEED_API_CONCURRENCY", "12")
+			Expect(InitConfig().NeedAPIConcurrency).To(Equal(12))
+
+			os.Setenv("NEED_API_CONCURRENCY", "-1")
+			Expect(InitConfig().NeedAPIConcurrency).To(Equal(4))
+
+			os.Unsetenv("NEED_API_CONCURRENCY")
+		})
+
+		It("reads timeouts as durations, ignoring invalid values", func() {
+			os.Setenv("REQUEST_TIMEOUT", "2s")
+			os.Setenv("NEED_API_TIMEOUT", "250ms")
+			os.Setenv("PERFORMANCE_API_TIMEOUT", "soon")
+			os.Setenv("CONTENT_STORE_TIMEOUT", "0")
+			os.Setenv("ARTEFACT_CACHE_TTL", "0")
+
+			config := InitConfig()
+			Expect(config.RequestTimeout).To(Equal(2 * time.Second))
+			Expect(config.NeedAPITimeout).To(Equal(250 * time.Millisecond))
+			Expect(config.PerformanceAPITimeout).To(Equal(6 * time.Second))
+			Expect(config.ContentStoreTimeout).To(Equal(3 * time.Second))
+			Expect(config.ArtefactCacheTTL).To(Equal(time.Duration(0)))
+
+			os.Unsetenv("REQUEST_TIMEOUT")
+			os.Unsetenv("NEED_API_TIMEOUT")
+			os.Unsetenv("PERFORMANCE_API_TIMEOUT")
+			os.Unsetenv("CONTENT_STORE_TIMEOUT")
+			os.Unsetenv("ARTEFACT_CACHE_TTL")
+		})
 	})
 })
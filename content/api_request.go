@@ -1,13 +1,19 @@
 package content
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
+// DefaultTimeout bounds any single request made by ApiRequest, as a backstop
+// for callers that don't set a deadline on their context.
+var DefaultTimeout = 30 * time.Second
+
 type JSONRequest interface {
-	GetJSON(url string, bearerToken string) (string, error)
+	GetJSON(ctx context.Context, url string, bearerToken string) (string, error)
 }
 
 type StatusError struct {
@@ -20,15 +26,22 @@ func (e StatusError) Error() string {
 
 type ApiRequest struct{}
 
-func (api ApiRequest) GetJSON(url string, bearerToken string) (string, error) {
-	resp, err := http.Get(url)
+func (api ApiRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: DefaultTimeout}
+	resp, err := client.Do(request.WithContext(ctx))
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode/100 != 2 {
 		return "", StatusError{resp.StatusCode}
 	}
 	body, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
 	return string(body), err
 }
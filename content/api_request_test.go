@@ -1,6 +1,8 @@
 package content_test
 
 import (
+	"context"
+
 	. "github.com/alphagov/metadata-api/content"
 	"github.com/jarcoal/httpmock"
 
@@ -22,7 +24,7 @@ var _ = Describe("ApiRequest", func() {
 				httpmock.RegisterResponder("GET", url,
 					httpmock.NewStringResponder(200, response))
 
-				responseString, err := apiRequest.GetJSON(url, "")
+				responseString, err := apiRequest.GetJSON(context.Background(), url, "")
 				Expect(err).To(BeNil())
 				Expect(responseString).To(Equal(response))
 			})
@@ -35,7 +37,7 @@ var _ = Describe("ApiRequest", func() {
 				httpmock.RegisterResponder("GET", url,
 					httpmock.NewStringResponder(404, ""))
 
-				responseString, err := apiRequest.GetJSON(url, "")
+				responseString, err := apiRequest.GetJSON(context.Background(), url, "")
 				stErr, _ := err.(StatusError)
 				Expect(responseString).To(Equal(""))
 				Expect(stErr.StatusCode).To(Equal(404))
@@ -1,10 +1,19 @@
 package content
 
-import ()
+import (
+	"time"
+)
 
 type Part struct {
 	WebURL string `json:"web_url"`
 	Title  string `json:"title"`
+
+	// Current is set on the part that was asked for, when a part's path
+	// was looked up rather than the artefact's.
+	Current bool `json:"current,omitempty"`
+
+	// Slug is the part's path relative to the artefact's.
+	Slug string `json:"-"`
 }
 
 type Detail struct {
@@ -14,10 +23,96 @@ type Detail struct {
 	Parts               []Part   `json:"parts"`
 }
 
+// Link is another content item that an artefact is tagged to. WebURL is
+// empty for items that aren't on GOV.UK.
+type Link struct {
+	ContentID string `json:"content_id"`
+	Title     string `json:"title"`
+	WebURL    string `json:"web_url,omitempty"`
+
+	// BasePath is where the item can be fetched from content-store.
+	BasePath string `json:"-"`
+}
+
+// ExpandedLink is a linked content item as content-store has it. Links are
+// its own links, if they were expanded too. Cycle is set instead if the
+// item was reached from itself, so its links would never end.
+type ExpandedLink struct {
+	ContentID string                     `json:"content_id"`
+	Title     string                     `json:"title"`
+	BasePath  string                     `json:"base_path"`
+	Links     map[string][]*ExpandedLink `json:"links,omitempty"`
+	Cycle     bool                       `json:"cycle,omitempty"`
+}
+
+// Translation is a version of an artefact in one locale, which may be the
+// artefact itself.
+type Translation struct {
+	Locale string `json:"locale"`
+	Title  string `json:"title"`
+	WebURL string `json:"web_url"`
+
+	// BasePath is where the translation can be fetched from content-store.
+	BasePath string `json:"-"`
+}
+
+// WithdrawnNotice explains why content was withdrawn, and when.
+type WithdrawnNotice struct {
+	Explanation string     `json:"explanation"`
+	WithdrawnAt *time.Time `json:"withdrawn_at"`
+}
+
 type Artefact struct {
-	ID      string `json:"id"`
-	WebURL  string `json:"web_url"`
-	Title   string `json:"title"`
-	Format  string `json:"format"`
+	ID     string `json:"id"`
+	WebURL string `json:"web_url"`
+	Title  string `json:"title"`
+	Format string `json:"format"`
+
+	SchemaName       string     `json:"schema_name"`
+	Locale           string     `json:"locale"`
+	Phase            string     `json:"phase"`
+	FirstPublishedAt *time.Time `json:"first_published_at"`
+	PublicUpdatedAt  *time.Time `json:"public_updated_at"`
+
+	// WithdrawnNotice is only set for content that has been withdrawn.
+	WithdrawnNotice *WithdrawnNotice `json:"withdrawn_notice,omitempty"`
+
+	Organisations         []Link `json:"organisations"`
+	Taxons                []Link `json:"taxons"`
+	MainstreamBrowsePages []Link `json:"mainstream_browse_pages"`
+
+	AvailableTranslations []Translation `json:"available_translations"`
+
 	Details Detail `json:"details"`
+
+	// Links are all the links read from content-store, by link type, for
+	// expanding.
+	Links map[string][]Link `json:"-"`
+}
+
+// WithCurrentPart is a copy of the artefact with the part at slug marked
+// Current. It's false if there's no such part.
+func (artefact *Artefact) WithCurrentPart(slug string) (*Artefact, bool) {
+	for i, part := range artefact.Details.Parts {
+		if part.Slug == "" || part.Slug != slug {
+			continue
+		}
+
+		withPart := *artefact
+		withPart.Details.Parts = make([]Part, len(artefact.Details.Parts))
+		copy(withPart.Details.Parts, artefact.Details.Parts)
+		withPart.Details.Parts[i].Current = true
+		return &withPart, true
+	}
+	return nil, false
+}
+
+// CurrentPart is the part marked Current, if any.
+func (artefact *Artefact) CurrentPart() *Part {
+	for i := range artefact.Details.Parts {
+		if artefact.Details.Parts[i].Current {
+			return &artefact.Details.Parts[i]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,37 @@
+package content_test
+
+import (
+	. "github.com/alphagov/metadata-api/content"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Artefact", func() {
+	var artefact *Artefact
+
+	BeforeEach(func() {
+		artefact = &Artefact{Details: Detail{Parts: []Part{
+			{Title: "Overview", Slug: "overview"},
+			{Title: "Rates", Slug: "rates"},
+		}}}
+	})
+
+	Describe("WithCurrentPart", func() {
+		It("marks the part at the path on a copy", func() {
+			withPart, ok := artefact.WithCurrentPart("rates")
+			Expect(ok).To(BeTrue())
+			Expect(withPart.CurrentPart()).To(Equal(&Part{Title: "Rates", Slug: "rates", Current: true}))
+			Expect(withPart.Details.Parts[0].Current).To(BeFalse())
+
+			Expect(artefact.CurrentPart()).To(BeNil())
+		})
+
+		It("is false for paths that aren't a part", func() {
+			for _, rest := range []string{"ratesx", "rates/print", ""} {
+				_, ok := artefact.WithCurrentPart(rest)
+				Expect(ok).To(BeFalse(), rest)
+			}
+		})
+	})
+})
@@ -0,0 +1,279 @@
+package content_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ContentItem is the subset of a content-store item that we read. Pointer
+// and slice fields are optional: content-store may omit them or send null.
+// Redirect and gone items only have a base path, schema name and, for
+// redirects, their Redirects.
+type ContentItem struct {
+	ContentID        string
+	Title            string
+	DocumentType     string
+	BasePath         string
+	SchemaName       *string
+	Description      *string
+	Locale           *string
+	Phase            *string
+	FirstPublishedAt *time.Time
+	PublicUpdatedAt  *time.Time
+	WithdrawnNotice  *ContentItemWithdrawnNotice
+	NeedIDs          []string
+	Details          *ContentItemDetails
+	Links            *ContentItemLinks
+	Redirects        []ContentItemRedirect
+}
+
+type ContentItemWithdrawnNotice struct {
+	Explanation string
+	WithdrawnAt *time.Time
+}
+
+// ContentItemRedirect sends requests for Path elsewhere. Type is "exact",
+// or "prefix" to also redirect paths below it, keeping the rest of the path
+// unless SegmentsMode is "ignore".
+type ContentItemRedirect struct {
+	Path         string
+	Type         string
+	Destination  string
+	SegmentsMode *string
+}
+
+type ContentItemDetails struct {
+	Parts []ContentItemPart
+}
+
+type ContentItemPart struct {
+	Slug  string
+	Title string
+}
+
+// ContentItemLinks are the links from an item that we read, by link type.
+type ContentItemLinks struct {
+	Organisations         []ContentItemLink
+	Taxons                []ContentItemLink
+	MainstreamBrowsePages []ContentItemLink
+	Parent                []ContentItemLink
+	AvailableTranslations []ContentItemTranslation
+}
+
+type ContentItemLink struct {
+	ContentID string
+	Title     string
+	BasePath  *string
+}
+
+// ContentItemTranslation is a version of an item in one locale, which may be
+// the item itself.
+type ContentItemTranslation struct {
+	Locale   string
+	Title    string
+	BasePath string
+}
+
+// ParseError is returned when a content-store response can't be decoded
+// into a ContentItem. Field is empty if the body wasn't a JSON object.
+type ParseError struct {
+	BasePath string
+	Field    string
+	Reason   string
+}
+
+func (e ParseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("content item %s: %s", e.BasePath, e.Reason)
+	}
+	return fmt.Sprintf("content item %s: field %q %s", e.BasePath, e.Field, e.Reason)
+}
+
+type jsonFields map[string]json.RawMessage
+
+// fieldDecoder decodes fields one at a time so that failures can name the
+// offending field. Once a field fails every later call is a no-op.
+type fieldDecoder struct {
+	basePath string
+	err      error
+}
+
+func (d *fieldDecoder) required(fields jsonFields, prefix, name string, v interface{}) {
+	d.decode(fields, prefix, name, v, true)
+}
+
+func (d *fieldDecoder) optional(fields jsonFields, prefix, name string, v interface{}) {
+	d.decode(fields, prefix, name, v, false)
+}
+
+func (d *fieldDecoder) decode(fields jsonFields, prefix, name string, v interface{}, required bool) {
+	if d.err != nil {
+		return
+	}
+
+	raw, ok := fields[name]
+	if !ok || string(raw) == "null" {
+		if required {
+			d.fail(prefix+name, "is missing")
+		}
+		return
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.fail(prefix+name, "has an unexpected type: "+err.Error())
+	}
+}
+
+func (d *fieldDecoder) fail(field, reason string) {
+	d.err = ParseError{BasePath: d.basePath, Field: field, Reason: reason}
+}
+
+// ParseContentItem decodes a content-store response body. slug is only used
+// to identify the item in errors when the body has no usable base_path.
+func ParseContentItem(slug string, response []byte) (*ContentItem, error) {
+	var fields jsonFields
+	if err := json.Unmarshal(response, &fields); err != nil {
+		return nil, ParseError{BasePath: slug, Reason: "is not a JSON object: " + err.Error()}
+	}
+
+	item := &ContentItem{}
+	decoder := &fieldDecoder{basePath: slug}
+
+	decoder.required(fields, "", "base_path", &item.BasePath)
+	if decoder.err == nil {
+		decoder.basePath = item.BasePath
+	}
+
+	decoder.optional(fields, "", "schema_name", &item.SchemaName)
+	if item.SchemaName != nil && (*item.SchemaName == "redirect" || *item.SchemaName == "gone") {
+		decoder.redirects(fields, item)
+		if decoder.err != nil {
+			return nil, decoder.err
+		}
+		return item, nil
+	}
+
+	decoder.required(fields, "", "content_id", &item.ContentID)
+	decoder.required(fields, "", "title", &item.Title)
+	decoder.required(fields, "", "document_type", &item.DocumentType)
+	decoder.optional(fields, "", "description", &item.Description)
+	decoder.optional(fields, "", "locale", &item.Locale)
+	decoder.optional(fields, "", "phase", &item.Phase)
+	decoder.optional(fields, "", "first_published_at", &item.FirstPublishedAt)
+	decoder.optional(fields, "", "public_updated_at", &item.PublicUpdatedAt)
+
+	var withdrawnNotice jsonFields
+	decoder.optional(fields, "", "withdrawn_notice", &withdrawnNotice)
+	if len(withdrawnNotice) > 0 {
+		item.WithdrawnNotice = &ContentItemWithdrawnNotice{}
+		decoder.required(withdrawnNotice, "withdrawn_notice.", "explanation", &item.WithdrawnNotice.Explanation)
+		decoder.optional(withdrawnNotice, "withdrawn_notice.", "withdrawn_at", &item.WithdrawnNotice.WithdrawnAt)
+	}
+
+	decoder.optional(fields, "", "need_ids", &item.NeedIDs)
+
+	var details jsonFields
+	decoder.optional(fields, "", "details", &details)
+	if details != nil {
+		item.Details = &ContentItemDetails{}
+
+		var parts []jsonFields
+		decoder.optional(details, "details.", "parts", &parts)
+		for i, part := range parts {
+			prefix := fmt.Sprintf("details.parts[%d].", i)
+			if part == nil {
+				decoder.fail(prefix[:len(prefix)-1], "is null")
+				break
+			}
+
+			itemPart := ContentItemPart{}
+			decoder.required(part, prefix, "slug", &itemPart.Slug)
+			decoder.required(part, prefix, "title", &itemPart.Title)
+			item.Details.Parts = append(item.Details.Parts, itemPart)
+		}
+	}
+
+	var links jsonFields
+	decoder.optional(fields, "", "links", &links)
+	if links != nil {
+		item.Links = &ContentItemLinks{
+			Organisations:         decoder.links(links, "organisations"),
+			Taxons:                decoder.links(links, "taxons"),
+			MainstreamBrowsePages: decoder.links(links, "mainstream_browse_pages"),
+			Parent:                decoder.links(links, "parent"),
+			AvailableTranslations: decoder.translations(links),
+		}
+	}
+
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+
+	return item, nil
+}
+
+// links decodes the links of one type from an item's links.
+func (d *fieldDecoder) links(links jsonFields, linkType string) []ContentItemLink {
+	var linked []jsonFields
+	d.optional(links, "links.", linkType, &linked)
+
+	var result []ContentItemLink
+	for i, link := range linked {
+		prefix := fmt.Sprintf("links.%s[%d].", linkType, i)
+		if link == nil {
+			d.fail(prefix[:len(prefix)-1], "is null")
+			break
+		}
+
+		itemLink := ContentItemLink{}
+		d.required(link, prefix, "content_id", &itemLink.ContentID)
+		d.required(link, prefix, "title", &itemLink.Title)
+		d.optional(link, prefix, "base_path", &itemLink.BasePath)
+		result = append(result, itemLink)
+	}
+	return result
+}
+
+// translations decodes the available translations from an item's links.
+func (d *fieldDecoder) translations(links jsonFields) []ContentItemTranslation {
+	var linked []jsonFields
+	d.optional(links, "links.", "available_translations", &linked)
+
+	var result []ContentItemTranslation
+	for i, link := range linked {
+		prefix := fmt.Sprintf("links.available_translations[%d].", i)
+		if link == nil {
+			d.fail(prefix[:len(prefix)-1], "is null")
+			break
+		}
+
+		translation := ContentItemTranslation{}
+		d.required(link, prefix, "locale", &translation.Locale)
+		d.required(link, prefix, "title", &translation.Title)
+		d.required(link, prefix, "base_path", &translation.BasePath)
+		result = append(result, translation)
+	}
+	return result
+}
+
+// redirects decodes the redirects of a redirect item.
+func (d *fieldDecoder) redirects(fields jsonFields, item *ContentItem) {
+	var redirects []jsonFields
+	d.optional(fields, "", "redirects", &redirects)
+
+	for i, redirect := range redirects {
+		prefix := fmt.Sprintf("redirects[%d].", i)
+		if redirect == nil {
+			d.fail(prefix[:len(prefix)-1], "is null")
+			break
+		}
+
+		itemRedirect := ContentItemRedirect{}
+		d.required(redirect, prefix, "path", &itemRedirect.Path)
+		d.required(redirect, prefix, "type", &itemRedirect.Type)
+		d.required(redirect, prefix, "destination", &itemRedirect.Destination)
+		d.optional(redirect, prefix, "segments_mode", &itemRedirect.SegmentsMode)
+		item.Redirects = append(item.Redirects, itemRedirect)
+	}
+}
@@ -0,0 +1,219 @@
+package content_store_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+
+	. "github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/content_store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type bodyRequest struct {
+	body string
+}
+
+func (req bodyRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	return req.body, nil
+}
+
+// replacementValues are substituted for fields to check that wrong types and
+// nulls produce errors rather than panics.
+var replacementValues = []interface{}{
+	nil, 0, "", true, []interface{}{}, []interface{}{nil}, []interface{}{1},
+	map[string]interface{}{}, map[string]interface{}{"parts": nil},
+}
+
+func contentStoreFixtures() []string {
+	fixtures, _ := filepath.Glob("../fixtures/content_store_response*.json")
+	return fixtures
+}
+
+func expectNoPanic(body string) {
+	Expect(func() {
+		artefact, err := content_store.GetArtefact(context.Background(), "fuzz", bodyRequest{body})
+		if err == nil {
+			Expect(artefact).ToNot(BeNil())
+			return
+		}
+
+		Expect(artefact).To(BeNil())
+		switch err.(type) {
+		case content_store.ParseError, content_store.RedirectError, StatusError:
+		default:
+			Fail(fmt.Sprintf("unexpected error type %T: %v", err, err))
+		}
+	}).ToNot(Panic())
+}
+
+// mutations returns copies of item with each field, including those in
+// details and its first part, removed or replaced by every replacementValue.
+func mutations(item map[string]interface{}) []map[string]interface{} {
+	var results []map[string]interface{}
+
+	mutateMap := func(target map[string]interface{}, emit func()) {
+		for key, original := range target {
+			delete(target, key)
+			emit()
+			for _, value := range replacementValues {
+				target[key] = value
+				emit()
+			}
+			target[key] = original
+		}
+	}
+
+	snapshot := func() {
+		bytes, _ := json.Marshal(item)
+		var copied map[string]interface{}
+		json.Unmarshal(bytes, &copied)
+		results = append(results, copied)
+	}
+
+	mutateMap(item, snapshot)
+
+	if details, ok := item["details"].(map[string]interface{}); ok {
+		mutateMap(details, snapshot)
+
+		if parts, ok := details["parts"].([]interface{}); ok && len(parts) > 0 {
+			if part, ok := parts[0].(map[string]interface{}); ok {
+				mutateMap(part, snapshot)
+			}
+		}
+	}
+
+	return results
+}
+
+var _ = Describe("ParseContentItem", func() {
+	for _, fixture := range contentStoreFixtures() {
+		fixture := fixture
+
+		Context(filepath.Base(fixture), func() {
+			var body []byte
+
+			BeforeEach(func() {
+				body, _ = ioutil.ReadFile(fixture)
+			})
+
+			It("never panics when fields are missing, null or the wrong type", func() {
+				var item map[string]interface{}
+				if err := json.Unmarshal(body, &item); err != nil {
+					expectNoPanic(string(body))
+					return
+				}
+
+				for _, mutated := range mutations(item) {
+					bytes, _ := json.Marshal(mutated)
+					expectNoPanic(string(bytes))
+				}
+			})
+
+			It("never panics on truncated or corrupted bodies", func() {
+				random := rand.New(rand.NewSource(1))
+
+				for i := 0; i < 500; i++ {
+					corrupted := make([]byte, len(body))
+					copy(corrupted, body)
+
+					if len(corrupted) > 0 {
+						corrupted[random.Intn(len(corrupted))] = byte(random.Intn(256))
+						corrupted = corrupted[:random.Intn(len(corrupted)+1)]
+					}
+
+					expectNoPanic(string(corrupted))
+				}
+			})
+		})
+	}
+
+	type fieldCase struct {
+		description string
+		body        string
+		field       string
+	}
+
+	cases := []fieldCase{
+		{"missing content_id", `{"base_path": "/foo", "title": "Foo", "document_type": "guide"}`, "content_id"},
+		{"null title", `{"base_path": "/foo", "content_id": "id", "title": null, "document_type": "guide"}`, "title"},
+		{"numeric need_ids", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "need_ids": 1}`, "need_ids"},
+		{"a part without a slug", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "details": {"parts": [{"title": "One"}]}}`, "details.parts[0].slug"},
+		{"a null part", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "details": {"parts": [null]}}`, "details.parts[0]"},
+		{"a taxon without a title", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "links": {"taxons": [{"content_id": "t"}]}}`, "links.taxons[0].title"},
+		{"a withdrawn notice without an explanation", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "withdrawn_notice": {"withdrawn_at": "2017-05-02T10:15:00Z"}}`, "withdrawn_notice.explanation"},
+		{"a redirect without a destination", `{"base_path": "/foo", "schema_name": "redirect", "redirects": [{"path": "/foo", "type": "exact"}]}`, "redirects[0].destination"},
+		{"a translation without a base_path", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "links": {"available_translations": [{"locale": "cy", "title": "Foo"}]}}`, "links.available_translations[0].base_path"},
+		{"a malformed first_published_at", `{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "first_published_at": "yesterday"}`, "first_published_at"},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		It("names the field for "+c.description, func() {
+			item, err := content_store.ParseContentItem("/requested", []byte(c.body))
+			Expect(item).To(BeNil())
+
+			parseErr, ok := err.(content_store.ParseError)
+			Expect(ok).To(BeTrue())
+			Expect(parseErr.Field).To(Equal(c.field))
+			Expect(parseErr.BasePath).To(Equal("/foo"))
+		})
+	}
+
+	It("falls back to the requested path when base_path is unusable", func() {
+		_, err := content_store.ParseContentItem("/requested", []byte(`{"base_path": 1}`))
+
+		parseErr, ok := err.(content_store.ParseError)
+		Expect(ok).To(BeTrue())
+		Expect(parseErr.Field).To(Equal("base_path"))
+		Expect(parseErr.BasePath).To(Equal("/requested"))
+	})
+
+	It("treats need_ids, description and details as optional", func() {
+		item, err := content_store.ParseContentItem("/foo", []byte(
+			`{"base_path": "/foo", "content_id": "id", "title": "Foo", "document_type": "guide", "description": null}`))
+		Expect(err).To(BeNil())
+		Expect(item.NeedIDs).To(BeNil())
+		Expect(item.Description).To(BeNil())
+		Expect(item.Details).To(BeNil())
+		Expect(item.Links).To(BeNil())
+	})
+
+	It("reads redirect and gone items, which have no content of their own", func() {
+		body, _ := ioutil.ReadFile("../fixtures/content_store_response_redirect.json")
+		item, err := content_store.ParseContentItem("/old-volunteering-guide", body)
+		Expect(err).To(BeNil())
+		Expect(item.ContentID).To(BeEmpty())
+		Expect(item.Redirects).To(HaveLen(2))
+		Expect(item.Redirects[0]).To(Equal(content_store.ContentItemRedirect{
+			Path:        "/old-volunteering-guide",
+			Type:        "exact",
+			Destination: "/government/get-involved/take-part/volunteer",
+		}))
+
+		body, _ = ioutil.ReadFile("../fixtures/content_store_response_gone.json")
+		item, err = content_store.ParseContentItem("/old-charity-forms", body)
+		Expect(err).To(BeNil())
+		Expect(*item.SchemaName).To(Equal("gone"))
+	})
+
+	It("reads organisations, taxons and mainstream browse pages from links", func() {
+		item, err := content_store.ParseContentItem("/foo", []byte(`{"base_path": "/foo", "content_id": "id",
+			"title": "Foo", "document_type": "guide", "links": {"ordered_related_items": [{"locale": "cy"}],
+			"mainstream_browse_pages": [{"content_id": "b", "title": "Browse", "base_path": "/browse/b"}],
+			"organisations": [{"content_id": "o", "title": "Org"}]}}`))
+		Expect(err).To(BeNil())
+
+		browsePath := "/browse/b"
+		Expect(item.Links).To(Equal(&content_store.ContentItemLinks{
+			Organisations:         []content_store.ContentItemLink{{ContentID: "o", Title: "Org"}},
+			MainstreamBrowsePages: []content_store.ContentItemLink{{ContentID: "b", Title: "Browse", BasePath: &browsePath}},
+		}))
+	})
+})
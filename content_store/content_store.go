@@ -1,92 +1,234 @@
 package content_store
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strings"
 
 	. "github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/govuk_path"
 	"github.com/alphagov/plek/go"
 )
 
-func GetArtefact(slug string, api JSONRequest) (*Artefact, error) {
-	jsonResponse, err := getJSON(slug, api)
+// RedirectError is returned for content that has moved: Path redirects to
+// Destination, which is a path on GOV.UK or, rarely, a URL elsewhere.
+type RedirectError struct {
+	Path        string
+	Destination string
+}
+
+func (e RedirectError) Error() string {
+	return fmt.Sprintf("%s redirects to %s", e.Path, e.Destination)
+}
+
+// GetArtefact fetches the content item at slug as an Artefact. Placeholders
+// are a 404 StatusError and gone items a 410 one, and redirects are a
+// RedirectError.
+func GetArtefact(ctx context.Context, slug string, api JSONRequest) (*Artefact, error) {
+	jsonResponse, err := getJSON(ctx, slug, api)
 	if err != nil {
 		return nil, err
 	}
-	return parseJSON(jsonResponse)
+	return parseJSON(slug, jsonResponse)
 }
 
-func getJSON(slug string, api JSONRequest) (string, error) {
-	contentStoreBase := fmt.Sprintf("%s/content/", plek.FindURL("content-store"))
-	url := contentStoreBase + slug
-	json, err := api.GetJSON(url, "")
+func getJSON(ctx context.Context, slug string, api JSONRequest) (string, error) {
+	path, err := govuk_path.Escape(slug)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/content%s", plek.FindURL("content-store"), path)
+	json, err := api.GetJSON(ctx, url, "")
 	if err != nil {
 		return "", err
 	}
 	return json, err
 }
 
-func parseJSON(response string) (*Artefact, error) {
-	artefact := &Artefact{}
-	var jsonMap map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &jsonMap); err != nil {
+// GetContentItem fetches the content item at basePath, whatever its schema,
+// with the same errors as GetArtefact for items that aren't there.
+func GetContentItem(ctx context.Context, basePath string, api JSONRequest) (*ContentItem, error) {
+	jsonResponse, err := getJSON(ctx, basePath, api)
+	if err != nil {
 		return nil, err
 	}
 
-	schemaName := jsonMap["schema_name"]
-	if schemaName != nil {
-		if strings.Contains(schemaName.(string), "placeholder") {
-			return nil, StatusError{404}
-		}
+	item, err := ParseContentItem(basePath, []byte(jsonResponse))
+	if err != nil {
+		return nil, err
 	}
+	if err := checkItem(basePath, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
 
-	artefact.ID = jsonMap["content_id"].(string)
-	artefact.Title = jsonMap["title"].(string)
-	artefact.Format = jsonMap["document_type"].(string)
-	artefact.WebURL = webURL(jsonMap["base_path"].(string))
-	artefact.Details = unmarshalDetails(jsonMap)
-	artefact.Details.Parts = unmarshalParts(jsonMap, *artefact)
+func parseJSON(slug string, response string) (*Artefact, error) {
+	item, err := ParseContentItem(slug, []byte(response))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkItem(slug, item); err != nil {
+		return nil, err
+	}
+
+	artefact := &Artefact{}
+	artefact.ID = item.ContentID
+	artefact.Title = item.Title
+	artefact.Format = item.DocumentType
+	artefact.SchemaName = stringValue(item.SchemaName)
+	artefact.Locale = stringValue(item.Locale)
+	artefact.Phase = stringValue(item.Phase)
+	artefact.FirstPublishedAt = item.FirstPublishedAt
+	artefact.PublicUpdatedAt = item.PublicUpdatedAt
+	if item.WithdrawnNotice != nil {
+		artefact.WithdrawnNotice = &WithdrawnNotice{
+			Explanation: item.WithdrawnNotice.Explanation,
+			WithdrawnAt: item.WithdrawnNotice.WithdrawnAt,
+		}
+	}
+	artefact.WebURL = webURL(item.BasePath)
+	artefact.Links = unmarshalLinks(item)
+	artefact.Organisations = artefact.Links["organisations"]
+	artefact.Taxons = artefact.Links["taxons"]
+	artefact.MainstreamBrowsePages = artefact.Links["mainstream_browse_pages"]
+	artefact.AvailableTranslations = unmarshalTranslations(item)
+	artefact.Details = unmarshalDetails(item)
+	artefact.Details.Parts = unmarshalParts(item, *artefact)
 
 	return artefact, nil
 }
 
+// checkItem returns the error for an item fetched from slug that isn't
+// content in its own right.
+func checkItem(slug string, item *ContentItem) error {
+	schemaName := stringValue(item.SchemaName)
+
+	switch {
+	case schemaName == "redirect" && len(item.Redirects) == 0:
+		return ParseError{BasePath: item.BasePath, Field: "redirects", Reason: "is missing"}
+	case schemaName == "redirect":
+		return RedirectError{Path: slug, Destination: redirectDestination(slug, item.Redirects)}
+	case schemaName == "gone":
+		return StatusError{StatusCode: 410}
+	case schemaName == "vanish" || strings.Contains(schemaName, "placeholder"):
+		return StatusError{StatusCode: 404}
+	}
+	return nil
+}
+
+// redirectDestination is where the most specific of redirects, of which
+// there's at least one, that matches slug sends it. Prefix redirects keep
+// the part of slug below their path unless told to ignore it.
+func redirectDestination(slug string, redirects []ContentItemRedirect) string {
+	path := "/" + strings.TrimPrefix(slug, "/")
+
+	var best *ContentItemRedirect
+	for i, redirect := range redirects {
+		matches := redirect.Path == path ||
+			(redirect.Type == "prefix" && strings.HasPrefix(path, strings.TrimSuffix(redirect.Path, "/")+"/"))
+		if matches && (best == nil || len(redirect.Path) > len(best.Path)) {
+			best = &redirects[i]
+		}
+	}
+
+	if best == nil {
+		return redirects[0].Destination
+	}
+
+	below := path[len(strings.TrimSuffix(best.Path, "/")):]
+	if best.Type == "prefix" && below != "" && stringValue(best.SegmentsMode) != "ignore" {
+		return strings.TrimSuffix(best.Destination, "/") + below
+	}
+	return best.Destination
+}
+
 func webURL(basePath string) string {
 	webroot, _ := plek.WebsiteRoot()
 	return fmt.Sprintf("%s%s", webroot, basePath)
 }
 
-func unmarshalDetails(jsonMap map[string]interface{}) Detail {
+func unmarshalDetails(item *ContentItem) Detail {
 	detail := Detail{}
-	needIds := jsonMap["need_ids"].([]interface{})
-	stringNeedIds := make([]string, len(needIds))
 
-	for i := range needIds {
-		stringNeedIds[i] = needIds[i].(string)
-	}
+	detail.NeedIDs = make([]string, len(item.NeedIDs))
+	copy(detail.NeedIDs, item.NeedIDs)
 
-	detail.NeedIDs = stringNeedIds
-	detail.Description = jsonMap["description"].(string)
+	if item.Description != nil {
+		detail.Description = *item.Description
+	}
 
 	return detail
 }
 
-func unmarshalParts(jsonMap map[string]interface{}, artefact Artefact) []Part {
-	jsonDetails := jsonMap["details"].(map[string]interface{})
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// unmarshalLinks returns the links from an item by link type, with an empty
+// list for each type of link it has none of.
+func unmarshalLinks(item *ContentItem) map[string][]Link {
+	links := &ContentItemLinks{}
+	if item.Links != nil {
+		links = item.Links
+	}
 
-	jsonParts, ok := jsonDetails["parts"].([]interface{})
+	return map[string][]Link{
+		"organisations":           toLinks(links.Organisations),
+		"taxons":                  toLinks(links.Taxons),
+		"mainstream_browse_pages": toLinks(links.MainstreamBrowsePages),
+		"parent":                  toLinks(links.Parent),
+	}
+}
 
-	if ok {
-		parts := []Part{}
+// unmarshalTranslations returns the translations of an item, which is an
+// empty list if content-store doesn't list any.
+func unmarshalTranslations(item *ContentItem) []Translation {
+	translations := []Translation{}
+	if item.Links == nil {
+		return translations
+	}
 
-		for i := range jsonParts {
-			jsonPart := jsonParts[i].(map[string]interface{})
-			part := Part{}
-			part.WebURL = fmt.Sprintf("%s/%s", artefact.WebURL, jsonPart["slug"].(string))
-			part.Title = jsonPart["title"].(string)
-			parts = append(parts, part)
+	for _, itemTranslation := range item.Links.AvailableTranslations {
+		translations = append(translations, Translation{
+			Locale:   itemTranslation.Locale,
+			Title:    itemTranslation.Title,
+			WebURL:   webURL(itemTranslation.BasePath),
+			BasePath: itemTranslation.BasePath,
+		})
+	}
+	return translations
+}
+
+func toLinks(itemLinks []ContentItemLink) []Link {
+	links := []Link{}
+	for _, itemLink := range itemLinks {
+		link := Link{ContentID: itemLink.ContentID, Title: itemLink.Title}
+		if itemLink.BasePath != nil {
+			link.BasePath = *itemLink.BasePath
+			link.WebURL = webURL(link.BasePath)
 		}
+		links = append(links, link)
+	}
+	return links
+}
+
+func unmarshalParts(item *ContentItem, artefact Artefact) []Part {
+	parts := []Part{}
+	if item.Details == nil {
 		return parts
 	}
-	return []Part{}
+
+	for _, itemPart := range item.Details.Parts {
+		part := Part{}
+		part.WebURL = fmt.Sprintf("%s/%s", artefact.WebURL, itemPart.Slug)
+		part.Title = itemPart.Title
+		part.Slug = itemPart.Slug
+		parts = append(parts, part)
+	}
+	return parts
 }
@@ -1,11 +1,14 @@
 package content_store_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"time"
 
 	. "github.com/alphagov/metadata-api/content"
 	"github.com/alphagov/metadata-api/content_store"
+	"github.com/alphagov/metadata-api/govuk_path"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,13 +19,17 @@ type stubRequest struct {
 	bearerToken string
 }
 
-func (req stubRequest) GetJSON(url string, bearerToken string) (string, error) {
+func (req stubRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
 	base_url := "http://content-store.dev.gov.uk/content/"
 	known_url := base_url + "known"
 	unknown_url := base_url + "unknown"
 	five_hundred_url := base_url + "five_hundred"
 	invalid_response_url := base_url + "invalid_response"
 	placeholder := base_url + "placeholder"
+	redirect := base_url + "old-volunteering-guide"
+	redirectPrefix := base_url + "old-volunteering-guide/archive/2015/march"
+	gone := base_url + "old-charity-forms"
+	goneStatus := base_url + "gone_status"
 
 	validResponseBytes, _ := ioutil.ReadFile("../fixtures/content_store_response.json")
 	validJSONResponse := string(validResponseBytes)
@@ -33,6 +40,9 @@ func (req stubRequest) GetJSON(url string, bearerToken string) (string, error) {
 	placeholderResponseBytes, _ := ioutil.ReadFile("../fixtures/content_store_response_placeholder.json")
 	placeholderJSONResponse := string(placeholderResponseBytes)
 
+	redirectResponseBytes, _ := ioutil.ReadFile("../fixtures/content_store_response_redirect.json")
+	goneResponseBytes, _ := ioutil.ReadFile("../fixtures/content_store_response_gone.json")
+
 	if url == known_url {
 		return validJSONResponse, nil
 	} else if url == invalid_response_url {
@@ -43,6 +53,12 @@ func (req stubRequest) GetJSON(url string, bearerToken string) (string, error) {
 		return "", StatusError{500}
 	} else if url == placeholder {
 		return placeholderJSONResponse, nil
+	} else if url == redirect || url == redirectPrefix {
+		return string(redirectResponseBytes), nil
+	} else if url == gone {
+		return string(goneResponseBytes), nil
+	} else if url == goneStatus {
+		return "", StatusError{StatusCode: 410}
 	} else {
 		return "", nil
 	}
@@ -55,7 +71,7 @@ var _ = Describe("content_store", func() {
 		Context("successful request", func() {
 			It("requests and returns the the artefact", func() {
 				os.Setenv("GOVUK_WEBSITE_ROOT", "http://dev.gov.uk")
-				artefact, err := content_store.GetArtefact("known", stub)
+				artefact, err := content_store.GetArtefact(context.Background(), "known", stub)
 				Expect(err).To(BeNil())
 				Expect(artefact.ID).To(Equal("73940c62-2580-42b1-9c22-f8e85b71065d"))
 				Expect(artefact.WebURL).To(Equal("http://dev.gov.uk/government/get-involved/take-part/volunteer"))
@@ -65,11 +81,55 @@ var _ = Describe("content_store", func() {
 				Expect(artefact.Details.BusinessProposition).To(Equal(false))
 				Expect(artefact.Details.Description).To(Equal("Find out how to volunteer in your local community and give your time to help others."))
 			})
+
+			It("reads when and how the content is published, and what it's tagged to", func() {
+				os.Setenv("GOVUK_WEBSITE_ROOT", "http://dev.gov.uk")
+				artefact, err := content_store.GetArtefact(context.Background(), "known", stub)
+				Expect(err).To(BeNil())
+				Expect(artefact.SchemaName).To(Equal("take_part"))
+				Expect(artefact.Locale).To(Equal("en"))
+				Expect(artefact.Phase).To(Equal("live"))
+				Expect(*artefact.FirstPublishedAt).To(BeTemporally("==", time.Date(2016, 2, 29, 9, 24, 10, 0, time.UTC)))
+				Expect(*artefact.PublicUpdatedAt).To(BeTemporally("==", time.Date(2017, 3, 23, 12, 5, 3, 0, time.UTC)))
+				Expect(artefact.Organisations).To(Equal([]Link{{
+					ContentID: "96ae61d6-c2a1-48cb-8e67-da9d105ae381",
+					Title:     "Cabinet Office",
+					WebURL:    "http://dev.gov.uk/government/organisations/cabinet-office",
+					BasePath:  "/government/organisations/cabinet-office",
+				}}))
+				Expect(artefact.Taxons).To(Equal([]Link{{
+					ContentID: "3a9a1bb8-47b7-48de-8ea4-a2fc6a5d4e43",
+					Title:     "Charities, volunteering and honours",
+					WebURL:    "http://dev.gov.uk/society-and-culture/charities-honours",
+					BasePath:  "/society-and-culture/charities-honours",
+				}}))
+				Expect(artefact.MainstreamBrowsePages).To(Equal([]Link{}))
+			})
+
+			It("lists the translations of the content, including itself", func() {
+				os.Setenv("GOVUK_WEBSITE_ROOT", "http://dev.gov.uk")
+				artefact, err := content_store.GetArtefact(context.Background(), "known", stub)
+				Expect(err).To(BeNil())
+				Expect(artefact.AvailableTranslations).To(Equal([]Translation{
+					{
+						Locale:   "en",
+						Title:    "Volunteer",
+						WebURL:   "http://dev.gov.uk/government/get-involved/take-part/volunteer",
+						BasePath: "/government/get-involved/take-part/volunteer",
+					},
+					{
+						Locale:   "cy",
+						Title:    "Gwirfoddoli",
+						WebURL:   "http://dev.gov.uk/government/get-involved/take-part/volunteer.cy",
+						BasePath: "/government/get-involved/take-part/volunteer.cy",
+					},
+				}))
+			})
 		})
 
 		Context("content not found", func() {
 			It("returns a 404 if the content isn't found", func() {
-				artefact, err := content_store.GetArtefact("unknown", stub)
+				artefact, err := content_store.GetArtefact(context.Background(), "unknown", stub)
 				Expect(err).NotTo(BeNil())
 				stErr, _ := err.(StatusError)
 				Expect(stErr.StatusCode).To(Equal(404))
@@ -79,7 +139,7 @@ var _ = Describe("content_store", func() {
 
 		Context("request returns a 500", func() {
 			It("returns a 500 if the request raises an error", func() {
-				artefact, err := content_store.GetArtefact("five_hundred", stub)
+				artefact, err := content_store.GetArtefact(context.Background(), "five_hundred", stub)
 				Expect(err).NotTo(BeNil())
 				stErr, _ := err.(StatusError)
 				Expect(stErr.StatusCode).To(Equal(500))
@@ -89,7 +149,7 @@ var _ = Describe("content_store", func() {
 
 		Context("an invalid content item", func() {
 			It("returns an error", func() {
-				artefact, err := content_store.GetArtefact("invalid_response", stub)
+				artefact, err := content_store.GetArtefact(context.Background(), "invalid_response", stub)
 				Expect(err).NotTo(BeNil())
 				Expect(artefact).To(BeNil())
 			})
@@ -97,12 +157,78 @@ var _ = Describe("content_store", func() {
 
 		Context("a placeholder item is returned", func() {
 			It("returns a 404 and a nil artefact", func() {
-				artefact, err := content_store.GetArtefact("placeholder", stub)
+				artefact, err := content_store.GetArtefact(context.Background(), "placeholder", stub)
 				Expect(err).NotTo(BeNil())
 				stErr, _ := err.(StatusError)
 				Expect(stErr.StatusCode).To(Equal(404))
 				Expect(artefact).To(BeNil())
 			})
 		})
+
+		Context("a path that escapes the content-store prefix", func() {
+			It("is rejected without a request", func() {
+				artefact, err := content_store.GetArtefact(context.Background(), "/government/../../healthcheck", stub)
+				Expect(artefact).To(BeNil())
+				Expect(err).To(BeAssignableToTypeOf(govuk_path.Error{}))
+			})
+		})
+
+		Context("a redirect item is returned", func() {
+			It("returns where the content has moved to", func() {
+				artefact, err := content_store.GetArtefact(context.Background(), "old-volunteering-guide", stub)
+				Expect(artefact).To(BeNil())
+				Expect(err).To(Equal(content_store.RedirectError{
+					Path:        "old-volunteering-guide",
+					Destination: "/government/get-involved/take-part/volunteer",
+				}))
+			})
+
+			It("keeps the rest of the path for prefix redirects", func() {
+				_, err := content_store.GetArtefact(context.Background(), "old-volunteering-guide/archive/2015/march", stub)
+				redirect, ok := err.(content_store.RedirectError)
+				Expect(ok).To(BeTrue())
+				Expect(redirect.Destination).To(Equal("/volunteering/2015/march"))
+			})
+
+			It("ignores the rest of the path when the redirect says to", func() {
+				body := `{"base_path": "/a", "schema_name": "redirect", "document_type": "redirect", "redirects": [
+					{"path": "/a", "type": "prefix", "destination": "/b", "segments_mode": "ignore"}]}`
+				_, err := content_store.GetArtefact(context.Background(), "/a/c", bodyRequest{body})
+				Expect(err).To(Equal(content_store.RedirectError{Path: "/a/c", Destination: "/b"}))
+			})
+
+			It("returns a parse error for a redirect without redirects", func() {
+				body := `{"base_path": "/a", "schema_name": "redirect", "document_type": "redirect"}`
+				_, err := content_store.GetArtefact(context.Background(), "/a", bodyRequest{body})
+				Expect(err).To(Equal(content_store.ParseError{BasePath: "/a", Field: "redirects", Reason: "is missing"}))
+			})
+		})
+
+		Context("a gone item is returned", func() {
+			It("returns a 410 and a nil artefact", func() {
+				for _, slug := range []string{"old-charity-forms", "gone_status"} {
+					artefact, err := content_store.GetArtefact(context.Background(), slug, stub)
+					Expect(err).To(Equal(StatusError{StatusCode: 410}))
+					Expect(artefact).To(BeNil())
+				}
+			})
+		})
+
+		Context("withdrawn content is returned", func() {
+			It("includes the withdrawn notice", func() {
+				body := `{"base_path": "/a", "content_id": "id", "title": "A", "document_type": "guide",
+					"withdrawn_notice": {"explanation": "No longer current", "withdrawn_at": "2017-05-02T10:15:00Z"}}`
+				artefact, err := content_store.GetArtefact(context.Background(), "/a", bodyRequest{body})
+				Expect(err).To(BeNil())
+				Expect(artefact.WithdrawnNotice.Explanation).To(Equal("No longer current"))
+				Expect(*artefact.WithdrawnNotice.WithdrawnAt).To(BeTemporally("==", time.Date(2017, 5, 2, 10, 15, 0, 0, time.UTC)))
+			})
+
+			It("has no notice for content that hasn't been withdrawn", func() {
+				artefact, err := content_store.GetArtefact(context.Background(), "known", stub)
+				Expect(err).To(BeNil())
+				Expect(artefact.WithdrawnNotice).To(BeNil())
+			})
+		})
 	})
 })
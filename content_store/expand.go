@@ -0,0 +1,230 @@
+package content_store
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/errgroup"
+)
+
+// expandableLinkTypes are the types of link that can be expanded.
+var expandableLinkTypes = map[string]bool{
+	"organisations":           true,
+	"taxons":                  true,
+	"mainstream_browse_pages": true,
+	"parent":                  true,
+}
+
+const maxExpandDepth = 3
+
+// ExpandOptions choose which types of link to expand, and how many links
+// away from the item to follow them. The zero ExpandOptions expands nothing.
+type ExpandOptions struct {
+	LinkTypes []string
+	Depth     int
+}
+
+// ParseExpandOptions validates the expand and expand_depth parameters of a
+// request. expand is a comma-separated list of link types, and expand_depth
+// defaults to 1.
+func ParseExpandOptions(expand, depth string) (ExpandOptions, error) {
+	options := ExpandOptions{Depth: 1}
+
+	if depth != "" {
+		value, err := strconv.Atoi(depth)
+		if err != nil || value < 1 || value > maxExpandDepth {
+			return ExpandOptions{}, fmt.Errorf("expand_depth must be a number from 1 to %d, not %q",
+				maxExpandDepth, depth)
+		}
+		options.Depth = value
+	}
+
+	seen := make(map[string]bool)
+	for _, linkType := range strings.Split(expand, ",") {
+		linkType = strings.TrimSpace(linkType)
+		if linkType == "" || seen[linkType] {
+			continue
+		}
+		if !expandableLinkTypes[linkType] {
+			return ExpandOptions{}, fmt.Errorf(
+				"expand must list organisations, taxons, mainstream_browse_pages or parent, not %q", linkType)
+		}
+
+		seen[linkType] = true
+		options.LinkTypes = append(options.LinkTypes, linkType)
+	}
+	sort.Strings(options.LinkTypes)
+
+	if len(options.LinkTypes) == 0 {
+		return ExpandOptions{}, nil
+	}
+	return options, nil
+}
+
+// String identifies the options, for use in cache keys.
+func (options ExpandOptions) String() string {
+	return fmt.Sprintf("%s:%d", strings.Join(options.LinkTypes, ","), options.Depth)
+}
+
+// ItemFetcher fetches the content item at a base path, as GetContentItem
+// does. It may be called concurrently.
+type ItemFetcher func(ctx context.Context, basePath string) (*ContentItem, error)
+
+// linkExpander expands the links of one item. Each linked item is fetched
+// once however many times it's linked to, and no more than concurrency at
+// once.
+type linkExpander struct {
+	options ExpandOptions
+	fetch   ItemFetcher
+	slots   chan struct{}
+
+	mutex sync.Mutex
+	items map[string]*linkedItem
+}
+
+type linkedItem struct {
+	done chan struct{}
+	err  error
+
+	contentID, title, basePath string
+	links                      map[string][]Link
+}
+
+// ExpandLinks resolves the links of the types options choose from artefact,
+// fetching each linked item to find its content ID, title and base path, and
+// so on for their links up to options.Depth links away. Links to items that
+// have gone or moved are left as the artefact has them. A concurrency of
+// zero or less fetches every item at once.
+func ExpandLinks(ctx context.Context, artefact *Artefact, options ExpandOptions, concurrency int,
+	fetch ItemFetcher) (map[string][]*ExpandedLink, error) {
+	if len(options.LinkTypes) == 0 {
+		return nil, nil
+	}
+
+	expander := &linkExpander{options: options, fetch: fetch, items: make(map[string]*linkedItem)}
+	if concurrency > 0 {
+		expander.slots = make(chan struct{}, concurrency)
+	}
+
+	return expander.expand(ctx, artefact.Links, 1, map[string]bool{artefact.ID: true})
+}
+
+// expand resolves links, which are depth links away from the item. ancestors
+// are the content IDs of the items they were reached through.
+func (e *linkExpander) expand(ctx context.Context, links map[string][]Link, depth int,
+	ancestors map[string]bool) (map[string][]*ExpandedLink, error) {
+	group, ctx := errgroup.WithContext(ctx)
+	expanded := make(map[string][]*ExpandedLink, len(e.options.LinkTypes))
+
+	for _, linkType := range e.options.LinkTypes {
+		results := make([]*ExpandedLink, len(links[linkType]))
+		expanded[linkType] = results
+
+		for i, link := range links[linkType] {
+			i, link := i, link
+			group.Go(func() (err error) {
+				results[i], err = e.resolve(ctx, link, depth, ancestors)
+				return err
+			})
+		}
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func (e *linkExpander) resolve(ctx context.Context, link Link, depth int,
+	ancestors map[string]bool) (*ExpandedLink, error) {
+	expanded := &ExpandedLink{ContentID: link.ContentID, Title: link.Title, BasePath: link.BasePath}
+	if ancestors[link.ContentID] {
+		expanded.Cycle = true
+		return expanded, nil
+	}
+	if link.BasePath == "" {
+		return expanded, nil
+	}
+
+	item, err := e.item(ctx, link.BasePath)
+	if moved(err) {
+		return expanded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expanded.ContentID, expanded.Title, expanded.BasePath = item.contentID, item.title, item.basePath
+	if depth < e.options.Depth {
+		linkAncestors := map[string]bool{link.ContentID: true}
+		for ancestor := range ancestors {
+			linkAncestors[ancestor] = true
+		}
+
+		if expanded.Links, err = e.expand(ctx, item.links, depth+1, linkAncestors); err != nil {
+			return nil, err
+		}
+	}
+
+	return expanded, nil
+}
+
+// moved reports whether err means a linked item is no longer where the link
+// says: it has gone or redirects elsewhere.
+func moved(err error) bool {
+	switch err := err.(type) {
+	case StatusError:
+		return err.StatusCode == http.StatusNotFound || err.StatusCode == http.StatusGone
+	case RedirectError:
+		return true
+	}
+	return false
+}
+
+// item fetches the item at basePath, or waits for another call fetching it.
+func (e *linkExpander) item(ctx context.Context, basePath string) (*linkedItem, error) {
+	e.mutex.Lock()
+	item, fetching := e.items[basePath]
+	if !fetching {
+		item = &linkedItem{done: make(chan struct{})}
+		e.items[basePath] = item
+	}
+	e.mutex.Unlock()
+
+	if fetching {
+		select {
+		case <-item.done:
+			return item, item.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	defer close(item.done)
+
+	if e.slots != nil {
+		select {
+		case e.slots <- struct{}{}:
+			defer func() { <-e.slots }()
+		case <-ctx.Done():
+			item.err = ctx.Err()
+			return nil, item.err
+		}
+	}
+
+	contentItem, err := e.fetch(ctx, basePath)
+	if err != nil {
+		item.err = err
+		return nil, err
+	}
+
+	item.contentID, item.title, item.basePath = contentItem.ContentID, contentItem.Title, contentItem.BasePath
+	item.links = unmarshalLinks(contentItem)
+	return item, nil
+}
@@ -0,0 +1,178 @@
+package content_store_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/content_store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func itemLink(contentID, basePath string) content_store.ContentItemLink {
+	return content_store.ContentItemLink{ContentID: contentID, Title: "Link to " + basePath, BasePath: &basePath}
+}
+
+// stubItems fetches items from a map by base path, counting the fetches of
+// each and how many were in flight at once.
+type stubItems struct {
+	items map[string]*content_store.ContentItem
+	delay time.Duration
+
+	mutex             sync.Mutex
+	fetches           map[string]int
+	inFlight, maxSeen int
+}
+
+func (s *stubItems) fetch(ctx context.Context, basePath string) (*content_store.ContentItem, error) {
+	s.mutex.Lock()
+	s.fetches[basePath]++
+	s.inFlight++
+	if s.inFlight > s.maxSeen {
+		s.maxSeen = s.inFlight
+	}
+	s.mutex.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mutex.Lock()
+	s.inFlight--
+	s.mutex.Unlock()
+
+	if basePath == "/broken" {
+		return nil, StatusError{StatusCode: 500}
+	}
+	item, ok := s.items[basePath]
+	if !ok {
+		return nil, StatusError{StatusCode: 404}
+	}
+	return item, nil
+}
+
+var _ = Describe("ExpandLinks", func() {
+	var (
+		stub     *stubItems
+		artefact *Artefact
+	)
+
+	expand := func(depth int, linkTypes ...string) (map[string][]*ExpandedLink, error) {
+		options := content_store.ExpandOptions{LinkTypes: linkTypes, Depth: depth}
+		return content_store.ExpandLinks(context.Background(), artefact, options, 2, stub.fetch)
+	}
+
+	BeforeEach(func() {
+		stub = &stubItems{
+			items: map[string]*content_store.ContentItem{
+				"/b": {ContentID: "b", Title: "B", BasePath: "/b", Links: &content_store.ContentItemLinks{
+					Parent: []content_store.ContentItemLink{itemLink("c", "/c")},
+				}},
+				"/c": {ContentID: "c", Title: "C", BasePath: "/c", Links: &content_store.ContentItemLinks{
+					Parent: []content_store.ContentItemLink{itemLink("a", "/a")},
+				}},
+				"/org": {ContentID: "org", Title: "Organisation", BasePath: "/org"},
+			},
+			fetches: make(map[string]int),
+		}
+
+		artefact = &Artefact{ID: "a", Links: map[string][]Link{
+			"parent":        {{ContentID: "b", Title: "Stale title", BasePath: "/b"}},
+			"organisations": {{ContentID: "org", BasePath: "/org"}, {ContentID: "gone", Title: "Gone", BasePath: "/gone"}},
+		}}
+	})
+
+	It("expands nothing unless asked to", func() {
+		links, err := expand(1)
+		Expect(err).To(BeNil())
+		Expect(links).To(BeNil())
+		Expect(stub.fetches).To(BeEmpty())
+	})
+
+	It("resolves links from the linked items, and no further than the depth", func() {
+		links, err := expand(1, "organisations", "parent", "taxons")
+		Expect(err).To(BeNil())
+		Expect(links).To(Equal(map[string][]*ExpandedLink{
+			"organisations": {
+				{ContentID: "org", Title: "Organisation", BasePath: "/org"},
+				{ContentID: "gone", Title: "Gone", BasePath: "/gone"},
+			},
+			"parent": {{ContentID: "b", Title: "B", BasePath: "/b"}},
+			"taxons": {},
+		}))
+		Expect(stub.fetches).ToNot(HaveKey("/c"))
+	})
+
+	It("follows links deeper, marking those back to an item on the way", func() {
+		links, err := expand(3, "parent")
+		Expect(err).To(BeNil())
+		Expect(links).To(Equal(map[string][]*ExpandedLink{
+			"parent": {{ContentID: "b", Title: "B", BasePath: "/b", Links: map[string][]*ExpandedLink{
+				"parent": {{ContentID: "c", Title: "C", BasePath: "/c", Links: map[string][]*ExpandedLink{
+					"parent": {{ContentID: "a", Title: "Link to /a", BasePath: "/a", Cycle: true}},
+				}}},
+			}}},
+		}))
+		Expect(stub.fetches).ToNot(HaveKey("/a"))
+	})
+
+	It("fetches each linked item once, a few at a time", func() {
+		stub.delay = 20 * time.Millisecond
+		artefact.Links["taxons"] = []Link{{ContentID: "b", BasePath: "/b"}, {ContentID: "org", BasePath: "/org"}}
+
+		_, err := expand(2, "organisations", "parent", "taxons")
+		Expect(err).To(BeNil())
+		Expect(stub.fetches).To(Equal(map[string]int{"/b": 1, "/c": 1, "/org": 1, "/gone": 1}))
+		Expect(stub.maxSeen).To(Equal(2))
+	})
+
+	It("fails if a linked item can't be fetched", func() {
+		artefact.Links["taxons"] = []Link{{ContentID: "broken", BasePath: "/broken"}}
+
+		links, err := expand(1, "taxons")
+		Expect(err).To(Equal(StatusError{StatusCode: 500}))
+		Expect(links).To(BeNil())
+	})
+
+	It("gives up when the context is done", func() {
+		stub.delay = 50 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		options := content_store.ExpandOptions{LinkTypes: []string{"organisations", "parent"}, Depth: 1}
+		_, err := content_store.ExpandLinks(ctx, artefact, options, 1, stub.fetch)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+})
+
+var _ = Describe("ParseExpandOptions", func() {
+	It("expands nothing by default", func() {
+		options, err := content_store.ParseExpandOptions("", "")
+		Expect(err).To(BeNil())
+		Expect(options).To(Equal(content_store.ExpandOptions{}))
+	})
+
+	It("reads link types, once each, and the depth", func() {
+		options, err := content_store.ParseExpandOptions("taxons, parent,taxons", "3")
+		Expect(err).To(BeNil())
+		Expect(options).To(Equal(content_store.ExpandOptions{LinkTypes: []string{"parent", "taxons"}, Depth: 3}))
+
+		options, err = content_store.ParseExpandOptions("organisations", "")
+		Expect(err).To(BeNil())
+		Expect(options.Depth).To(Equal(1))
+	})
+
+	for _, c := range []struct{ expand, depth string }{
+		{"people", ""},
+		{"taxons", "0"},
+		{"taxons", "4"},
+		{"taxons", "deep"},
+	} {
+		c := c
+		It("rejects expand="+c.expand+"&expand_depth="+c.depth, func() {
+			_, err := content_store.ParseExpandOptions(c.expand, c.depth)
+			Expect(err).ToNot(BeNil())
+		})
+	}
+})
@@ -0,0 +1,75 @@
+// Package errgroup runs a group of functions concurrently, keeping the first
+// error any of them returns and cancelling the rest.
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+type Group struct {
+	cancel func()
+
+	waitGroup sync.WaitGroup
+	semaphore chan struct{}
+
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext returns a new Group and a context derived from ctx. The context
+// is cancelled when a function passed to Go returns an error, or when Wait
+// returns, whichever happens first.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// SetLimit caps the number of functions running at once at n, so that Go
+// blocks until a running function returns. A limit of zero or less removes
+// the cap. It must not be called while functions are running.
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.semaphore = nil
+		return
+	}
+	g.semaphore = make(chan struct{}, n)
+}
+
+// Go calls f in a new goroutine, once the limit set by SetLimit allows.
+func (g *Group) Go(f func() error) {
+	if g.semaphore != nil {
+		g.semaphore <- struct{}{}
+	}
+	g.waitGroup.Add(1)
+
+	go func() {
+		defer g.done()
+
+		if err := f(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel()
+				}
+			})
+		}
+	}()
+}
+
+func (g *Group) done() {
+	if g.semaphore != nil {
+		<-g.semaphore
+	}
+	g.waitGroup.Done()
+}
+
+// Wait blocks until every function passed to Go has returned, then returns
+// the first error, if any.
+func (g *Group) Wait() error {
+	g.waitGroup.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return g.err
+}
@@ -0,0 +1,13 @@
+package errgroup_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestErrgroup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Errgroup Suite")
+}
@@ -0,0 +1,83 @@
+package errgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/alphagov/metadata-api/errgroup"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Group", func() {
+	It("waits for every function and returns nil if none fail", func() {
+		group, _ := WithContext(context.Background())
+		results := make([]int, 10)
+
+		for i := range results {
+			i := i
+			group.Go(func() error {
+				results[i] = i * i
+				return nil
+			})
+		}
+
+		Expect(group.Wait()).To(BeNil())
+		Expect(results[9]).To(Equal(81))
+	})
+
+	It("returns the first error and cancels the others", func() {
+		group, ctx := WithContext(context.Background())
+		failure := errors.New("failed")
+
+		group.Go(func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		})
+		group.Go(func() error {
+			return failure
+		})
+
+		Expect(group.Wait()).To(Equal(failure))
+		Expect(ctx.Err()).To(Equal(context.Canceled))
+	})
+
+	It("runs no more than the limit at once", func() {
+		group, _ := WithContext(context.Background())
+		group.SetLimit(2)
+
+		var running, maxRunning int32
+		for i := 0; i < 10; i++ {
+			group.Go(func() error {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}
+
+		Expect(group.Wait()).To(BeNil())
+		Expect(atomic.LoadInt32(&maxRunning)).To(Equal(int32(2)))
+	})
+
+	It("cancels the context once Wait returns", func() {
+		group, ctx := WithContext(context.Background())
+		group.Go(func() error { return nil })
+
+		Expect(group.Wait()).To(BeNil())
+		Expect(ctx.Err()).To(Equal(context.Canceled))
+	})
+})
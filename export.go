@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// exportLine is one line of an export: the Metadata for Path, or the body of
+// the error response for it, in V1's schema.
+type exportLine struct {
+	Path string `json:"path"`
+	*Metadata
+}
+
+// exportLineV2 is exportLine in V2's schema.
+type exportLineV2 struct {
+	Path string `json:"path"`
+	*MetadataV2
+}
+
+// newExportLine is the line for metadata at path in version's schema.
+func newExportLine(path string, metadata *Metadata, version Version) interface{} {
+	switch body := version.body(metadata).(type) {
+	case *MetadataV2:
+		return exportLineV2{path, body}
+	default:
+		return exportLine{path, body.(*Metadata)}
+	}
+}
+
+// ExportHandler streams newline-delimited JSON, one line for each path as
+// soon as it's fetched, so that the order of lines is not the order of the
+// paths. The paths are given either as a comma-separated "paths" parameter
+// to a GET, or as a JSON list in the body of a POST. Each line is in the
+// schema of the version the client Accepts, which is V1 by default. Fetching
+// stops if the client goes away.
+func ExportHandler(fetcher *Fetcher) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := negotiateVersion(r)
+
+		options, err := ParseInfoOptions(r.URL.Query())
+		if err != nil {
+			renderError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var slugs []string
+
+		switch r.Method {
+		case "GET":
+			for _, paths := range r.URL.Query()["paths"] {
+				for _, path := range strings.Split(paths, ",") {
+					if path != "" {
+						slugs = append(slugs, path)
+					}
+				}
+			}
+		case "POST":
+			if slugs, err = decodePaths(w, r); err != nil {
+				renderError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			renderError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+
+		fetcher.each(ctx, slugs, options, func(slug string, metadata *Metadata) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err := encoder.Encode(newExportLine(slug, metadata, version)); err != nil {
+				logging.WithField("error", err).Warn("abandoning export")
+				cancel()
+				return
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		})
+	}
+}
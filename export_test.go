@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/alphagov/metadata-api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// blockingJSONRequest holds requests for /slow until released or cancelled.
+type blockingJSONRequest struct {
+	pathJSONRequest
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func (items blockingJSONRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	if strings.HasSuffix(url, "/slow") {
+		select {
+		case <-items.release:
+		case <-ctx.Done():
+			close(items.cancelled)
+			return "", ctx.Err()
+		}
+	}
+	return items.pathJSONRequest.GetJSON(ctx, url, bearerToken)
+}
+
+var _ = Describe("Export", func() {
+	var (
+		exportServer, performanceAPI *httptest.Server
+		contentStore                 blockingJSONRequest
+	)
+
+	readLine := func(reader *bufio.Reader) map[string]interface{} {
+		line, err := reader.ReadString('\n')
+		Expect(err).To(BeNil())
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal([]byte(line), &result)).To(Succeed())
+		return result
+	}
+
+	BeforeEach(func() {
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":[]}`)
+		})
+
+		contentStore = blockingJSONRequest{
+			pathJSONRequest: pathJSONRequest{
+				"/one":  contentItem("/one"),
+				"/two":  contentItem("/two"),
+				"/slow": contentItem("/slow"),
+			},
+			release:   make(chan struct{}),
+			cancelled: make(chan struct{}),
+		}
+
+		fetcher := NewFetcher("", performanceAPI.URL, contentStore, &Config{BatchConcurrency: 2})
+		exportServer = testHandlerServer(ExportHandler(fetcher))
+	})
+
+	AfterEach(func() {
+		exportServer.Close()
+		performanceAPI.Close()
+	})
+
+	It("writes a line for each path given in the query", func() {
+		response, err := http.Get(exportServer.URL + "/export?paths=/one,/missing&paths=/two")
+		Expect(err).To(BeNil())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(HavePrefix("application/x-ndjson"))
+
+		body, _ := readResponseBody(response)
+		statuses := map[string]interface{}{}
+		for _, line := range strings.Split(body, "\n") {
+			var result map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &result)).To(Succeed())
+			statuses[result["path"].(string)] = result["_response_info"].(map[string]interface{})["status"]
+		}
+
+		Expect(statuses).To(Equal(map[string]interface{}{"/one": "ok", "/two": "ok", "/missing": "not found"}))
+	})
+
+	It("reads paths from the body of a POST", func() {
+		response, err := http.Post(exportServer.URL+"/export", "application/json", strings.NewReader(`["/one"]`))
+		Expect(err).To(BeNil())
+
+		result := readLine(bufio.NewReader(response.Body))
+		response.Body.Close()
+		Expect(result["path"]).To(Equal("/one"))
+		Expect(result["artefact"]).To(HaveKeyWithValue("title", "Title"))
+	})
+
+	It("writes each line in the schema of the version asked for", func() {
+		response, err := http.Get(exportServer.URL + "/export?paths=/one")
+		Expect(err).To(BeNil())
+		v1 := readLine(bufio.NewReader(response.Body))
+		response.Body.Close()
+		Expect(v1).To(HaveKey("_response_info"))
+		Expect(v1["artefact"]).ToNot(HaveKey("schema_name"))
+
+		request, _ := http.NewRequest("GET", exportServer.URL+"/export?paths=/one", nil)
+		request.Header.Set("Accept", "application/vnd.govuk.metadata.v2+json")
+		response, err = http.DefaultClient.Do(request)
+		Expect(err).To(BeNil())
+		v2 := readLine(bufio.NewReader(response.Body))
+		response.Body.Close()
+		Expect(v2["path"]).To(Equal("/one"))
+		Expect(v2).To(HaveKey("response_info"))
+		Expect(v2["artefact"]).To(HaveKey("schema_name"))
+	})
+
+	It("writes each line as soon as its path is ready", func() {
+		response, err := http.Get(exportServer.URL + "/export?paths=/slow,/one")
+		Expect(err).To(BeNil())
+		defer response.Body.Close()
+		reader := bufio.NewReader(response.Body)
+
+		Expect(readLine(reader)["path"]).To(Equal("/one"))
+		close(contentStore.release)
+		Expect(readLine(reader)["path"]).To(Equal("/slow"))
+	})
+
+	It("stops fetching when the client goes away", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		request, _ := http.NewRequest("GET", exportServer.URL+"/export?paths=/slow,/one", nil)
+
+		response, err := http.DefaultClient.Do(request.WithContext(ctx))
+		Expect(err).To(BeNil())
+		readLine(bufio.NewReader(response.Body))
+		cancel()
+
+		Eventually(contentStore.cancelled).Should(BeClosed())
+	})
+})
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alphagov/performanceplatform-client-go"
+
+	"github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/cache"
+	"github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/content_store"
+	"github.com/alphagov/metadata-api/errgroup"
+	"github.com/alphagov/metadata-api/govuk_path"
+	"github.com/alphagov/metadata-api/need_api"
+	"github.com/alphagov/metadata-api/performance_platform"
+	"github.com/alphagov/metadata-api/request"
+)
+
+// maxRedirects is how many redirects are followed for one request.
+const maxRedirects = 5
+
+// MetadataError is returned by Fetcher.Metadata when there's nothing worth
+// responding with. Status is the HTTP status to use. Location is set when
+// the content has moved, to where it has moved to.
+type MetadataError struct {
+	Status   int
+	Message  string
+	Location string
+}
+
+func (e *MetadataError) Error() string {
+	return e.Message
+}
+
+// metadata is the body of the error response.
+func (e *MetadataError) metadata() *Metadata {
+	metadata := errorMetadata(e.Message)
+	metadata.ResponseInfo.Location = e.Location
+	return metadata
+}
+
+// Fetcher gets each section of Metadata from its upstream API, caching the
+// results for as long as the Config allows. Cached values are shared between
+// requests and must not be modified.
+type Fetcher struct {
+	needClient     *need_api.Client
+	performanceAPI string
+	apiRequest     content.JSONRequest
+	config         *Config
+
+	artefacts   *cache.Cache
+	linkedItems *cache.Cache
+	needs       *cache.Cache
+	statistics  *cache.Cache
+
+	// lastGood holds the last complete Metadata for each slug, to be served
+	// while an upstream is failing.
+	lastGood   *cache.Cache
+	refreshing map[string]bool
+	refreshes  sync.WaitGroup
+	mutex      sync.Mutex
+}
+
+func NewFetcher(needAPI, performanceAPI string,
+	apiRequest content.JSONRequest, config *Config) *Fetcher {
+	fetcher := &Fetcher{
+		needClient:     need_api.NewClient(needAPI, config.BearerTokenNeedAPI, config.NeedAPIConcurrency),
+		performanceAPI: performanceAPI,
+		apiRequest:     apiRequest,
+		config:         config,
+
+		artefacts:   newUpstreamCache("artefacts", config.ArtefactCacheTTL, config.ContentStoreTimeout, config),
+		linkedItems: newUpstreamCache("linked_items", config.ArtefactCacheTTL, config.ContentStoreTimeout, config),
+		needs:       newUpstreamCache("needs", config.NeedCacheTTL, config.NeedAPITimeout, config),
+		statistics:  newUpstreamCache("statistics", config.StatisticsCacheTTL, config.PerformanceAPITimeout, config),
+
+		lastGood:   cache.New("last_good", config.StaleTTL, config.CacheSize, statsdClient),
+		refreshing: make(map[string]bool),
+	}
+	fetcher.needClient.Fetch = fetcher.need
+	return fetcher
+}
+
+// newUpstreamCache is a cache of values fetched from an upstream API, each
+// fetch bounded by timeout, or failing that by the overall request timeout.
+func newUpstreamCache(name string, ttl, timeout time.Duration, config *Config) *cache.Cache {
+	upstream := cache.New(name, ttl, config.CacheSize, statsdClient)
+	upstream.Timeout = timeout
+	if upstream.Timeout <= 0 {
+		upstream.Timeout = config.RequestTimeout
+	}
+	return upstream
+}
+
+// Info is what /info responds with for slug: its Metadata, or the last good
+// Metadata if an upstream failed and there is some. Any error is a
+// *MetadataError.
+func (fetcher *Fetcher) Info(ctx context.Context, slug string, options InfoOptions) (*Metadata, error) {
+	return fetcher.info(ctx, slug, options, fetcher.need)
+}
+
+func (fetcher *Fetcher) info(ctx context.Context, slug string, options InfoOptions,
+	fetchNeed need_api.NeedFetcher) (*Metadata, error) {
+	slug, err := normaliseSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if slug == "/" {
+		return nil, &MetadataError{Status: http.StatusNotFound, Message: "not found"}
+	}
+
+	// The caller's context is cancelled if the client goes away, which
+	// abandons any upstream calls still in flight.
+	ctx, cancel := withTimeout(ctx, fetcher.config.RequestTimeout)
+	defer cancel()
+
+	// Strict clients are told about the failure instead of being given
+	// stale data.
+	metadata, err := fetcher.metadata(ctx, slug, options, fetchNeed)
+	if !options.Strict && upstreamFailed(metadata, err) {
+		if stale, ok := fetcher.Stale(slug, options); ok {
+			fetcher.RefreshInBackground(slug, options)
+			return stale, nil
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Unless the client asks for strict behaviour, failures fetching needs
+	// or performance data are reported alongside whatever else could be
+	// fetched.
+	if options.Strict && len(metadata.Errors) > 0 {
+		status, message := metadata.Errors[0].strictError()
+		return nil, &MetadataError{Status: status, Message: message}
+	}
+
+	return metadata, nil
+}
+
+// normaliseSlug is the base path that slug, a path or GOV.UK URL as a
+// client gave it, is for. Any error is a *MetadataError.
+func normaliseSlug(slug string) (string, error) {
+	path, err := govuk_path.Normalise(slug)
+	if err != nil {
+		return "", &MetadataError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+	return path, nil
+}
+
+// Metadata fetches every section of Metadata for slug. Needs and performance
+// data are nice to have, so failing to fetch them is recorded in the
+// Metadata's Errors rather than returned. Any error is a *MetadataError.
+func (fetcher *Fetcher) Metadata(ctx context.Context, slug string, options InfoOptions) (*Metadata, error) {
+	return fetcher.metadata(ctx, slug, options, fetcher.need)
+}
+
+func (fetcher *Fetcher) metadata(ctx context.Context, slug string, options InfoOptions,
+	fetchNeed need_api.NeedFetcher) (*Metadata, error) {
+	config := fetcher.config
+	ctx, expiry := withCacheExpiry(ctx)
+
+	requestedSlug := slug
+	artefact, slug, redirected, err := fetcher.followArtefact(ctx, slug, options.FollowRedirects)
+	if err != nil {
+		return nil, err
+	}
+
+	artefact, slug, err = fetcher.translation(ctx, artefact, slug, options.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{
+		Artefact:     artefact,
+		ResponseInfo: &ResponseInfo{Status: "ok"},
+	}
+	if redirected {
+		metadata.ResponseInfo.RedirectedFrom = requestedSlug
+	}
+
+	needCtx, cancelNeeds := withTimeout(ctx, config.NeedAPITimeout)
+	defer cancelNeeds()
+	performanceCtx, cancelPerformance := withTimeout(ctx, config.PerformanceAPITimeout)
+	defer cancelPerformance()
+	linksCtx, cancelLinks := withTimeout(ctx, config.ContentStoreTimeout)
+	defer cancelLinks()
+
+	var (
+		waitGroup                         sync.WaitGroup
+		needs                             []*need_api.Need
+		performance                       *performance_platform.Statistics
+		links                             map[string][]*content.ExpandedLink
+		needErr, performanceErr, linksErr error
+	)
+
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+
+		needStart := time.Now()
+		needs, needErr = need_api.FetchNeeds(needCtx, artefact.Details.NeedIDs,
+			config.NeedAPIConcurrency, fetchNeed)
+		statsDTiming("needs", needStart, time.Now())
+	}()
+
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+
+		performance, performanceErr = fetcher.artefactPerformance(performanceCtx, slug, artefact, options)
+	}()
+
+	if len(options.Expand.LinkTypes) > 0 {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			linksStart := time.Now()
+			links, linksErr = content_store.ExpandLinks(linksCtx, artefact, options.Expand,
+				config.ContentStoreConcurrency, fetcher.linkedItem)
+			statsDTiming("links", linksStart, time.Now())
+		}()
+	}
+
+	waitGroup.Wait()
+
+	if needErr != nil {
+		metadata.AddError(newSectionError(NeedsSection, needCtx, needErr))
+	}
+	metadata.Needs = needs
+
+	if performanceErr != nil {
+		metadata.AddError(newSectionError(PerformanceSection, performanceCtx, performanceErr))
+	}
+	metadata.Performance = performance
+
+	if linksErr != nil {
+		metadata.AddError(newSectionError(LinksSection, linksCtx, linksErr))
+	}
+	metadata.Links = links
+
+	metadata.Anomalies = analysis.DetectAll(performance, options.Anomalies)
+	metadata.EmergingSearchTerms = analysis.DetectEmergingSearchTerms(performance,
+		options.SearchTerms.Limit, options.Anomalies)
+	metadata.expires = expiry.earliest()
+
+	if len(metadata.Errors) == 0 {
+		fetcher.lastGood.Set(lastGoodKey(requestedSlug, options), &lastGoodMetadata{metadata, time.Now().UTC()})
+	}
+
+	return metadata, nil
+}
+
+// fetchArtefact is Artefact within the content store timeout, returning a
+// *MetadataError if it fails.
+func (fetcher *Fetcher) fetchArtefact(ctx context.Context, slug string) (*content.Artefact, error) {
+	artefactStart := time.Now()
+	artefactCtx, cancelArtefact := withTimeout(ctx, fetcher.config.ContentStoreTimeout)
+	defer cancelArtefact()
+
+	artefact, err := fetcher.Artefact(artefactCtx, slug)
+	statsDTiming("artefact", artefactStart, time.Now())
+	if err != nil {
+		if isNotFound(err) {
+			err = request.NotFoundError
+		}
+
+		if statusErr, ok := err.(content.StatusError); ok && statusErr.StatusCode == http.StatusGone {
+			return nil, &MetadataError{Status: http.StatusGone, Message: "gone"}
+		}
+
+		if redirect, ok := err.(content_store.RedirectError); ok {
+			return nil, &MetadataError{
+				Status:   http.StatusMovedPermanently,
+				Message:  "moved permanently",
+				Location: redirect.Destination,
+			}
+		}
+
+		if err == request.NotFoundError {
+			return nil, &MetadataError{Status: http.StatusNotFound, Message: err.Error()}
+		}
+
+		if _, ok := err.(content_store.ParseError); ok {
+			return nil, &MetadataError{Status: http.StatusBadGateway, Message: "Artefact: " + err.Error()}
+		}
+
+		return nil, &MetadataError{Status: upstreamErrorStatus(artefactCtx), Message: "Artefact: " + err.Error()}
+	}
+
+	return artefact, nil
+}
+
+// partArtefact is fetchArtefact for slug or, if content-store doesn't know
+// it, for the closest parent path it does know. That's only found if it's
+// multipart content and slug is one of its parts, or beneath one, which is
+// then Current. It returns the path of the artefact or part found, which is
+// the part's own path for a path beneath it.
+func (fetcher *Fetcher) partArtefact(ctx context.Context, slug string) (*content.Artefact, string, error) {
+	artefact, err := fetcher.fetchArtefact(ctx, slug)
+	if metadataErr, ok := err.(*MetadataError); !ok || metadataErr.Status != http.StatusNotFound {
+		return artefact, slug, err
+	}
+
+	for parent := path.Dir(slug); parent != "/"; parent = path.Dir(parent) {
+		parentArtefact, parentErr := fetcher.fetchArtefact(ctx, parent)
+		if metadataErr, ok := parentErr.(*MetadataError); ok && metadataErr.Status == http.StatusNotFound {
+			continue
+		}
+		if parentErr != nil {
+			return nil, "", parentErr
+		}
+
+		partSlug := strings.SplitN(strings.TrimPrefix(slug, parent+"/"), "/", 2)[0]
+		if withPart, ok := parentArtefact.WithCurrentPart(partSlug); ok {
+			return withPart, parent + "/" + partSlug, nil
+		}
+		return nil, "", err
+	}
+	return nil, "", err
+}
+
+// followArtefact is partArtefact for slug or, if follow is set, for wherever
+// it redirects to on GOV.UK, returning the path it was found at and whether
+// a redirect was followed to get there.
+func (fetcher *Fetcher) followArtefact(ctx context.Context, slug string,
+	follow bool) (*content.Artefact, string, bool, error) {
+	seen := map[string]bool{slug: true}
+
+	for {
+		artefact, found, err := fetcher.partArtefact(ctx, slug)
+		metadataErr, ok := err.(*MetadataError)
+		if !follow || !ok || !strings.HasPrefix(metadataErr.Location, "/") {
+			return artefact, found, len(seen) > 1, err
+		}
+
+		slug = metadataErr.Location
+		if end := strings.IndexAny(slug, "?#"); end >= 0 {
+			slug = slug[:end]
+		}
+
+		if seen[slug] || len(seen) > maxRedirects {
+			return nil, "", true, &MetadataError{Status: http.StatusBadGateway, Message: "Artefact: too many redirects"}
+		}
+		seen[slug] = true
+	}
+}
+
+// translation is the translation of artefact, found at slug, in locale,
+// returning the slug it was found at. That's artefact itself if locale is
+// empty or its own. If a part of artefact was asked for, the same part of
+// the translation is.
+func (fetcher *Fetcher) translation(ctx context.Context, artefact *content.Artefact, slug string,
+	locale string) (*content.Artefact, string, error) {
+	if locale == "" || locale == artefact.Locale {
+		return artefact, slug, nil
+	}
+
+	for _, translation := range artefact.AvailableTranslations {
+		if translation.Locale == locale {
+			translated, err := fetcher.fetchArtefact(ctx, translation.BasePath)
+			if err != nil {
+				return nil, "", err
+			}
+
+			part := artefact.CurrentPart()
+			if part == nil {
+				return translated, translation.BasePath, nil
+			}
+			if translated, ok := translated.WithCurrentPart(part.Slug); ok {
+				return translated, translation.BasePath + "/" + part.Slug, nil
+			}
+			return nil, "", &MetadataError{Status: http.StatusNotFound, Message: "no translation in " + locale}
+		}
+	}
+
+	return nil, "", &MetadataError{Status: http.StatusNotFound, Message: "no translation in " + locale}
+}
+
+// Artefact is the artefact at slug. That content-store doesn't have one is
+// cached too, as each part of multipart content is looked for there first.
+func (fetcher *Fetcher) Artefact(ctx context.Context, slug string) (*content.Artefact, error) {
+	artefact, expires, err := fetcher.artefacts.FetchExpiring(ctx, slug, func(ctx context.Context) (interface{}, error) {
+		artefact, err := content_store.GetArtefact(ctx, slug, fetcher.apiRequest)
+		if isNotFound(err) {
+			return err, nil
+		}
+		return artefact, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	noteExpiry(ctx, expires)
+
+	if notFound, ok := artefact.(error); ok {
+		return nil, notFound
+	}
+	return artefact.(*content.Artefact), nil
+}
+
+// isNotFound is whether err says that content-store doesn't have an item.
+func isNotFound(err error) bool {
+	statusErr, ok := err.(content.StatusError)
+	return err == request.NotFoundError || (ok && statusErr.StatusCode == http.StatusNotFound)
+}
+
+// linkedItem is the content item at basePath, which an artefact links to.
+func (fetcher *Fetcher) linkedItem(ctx context.Context, basePath string) (*content_store.ContentItem, error) {
+	item, expires, err := fetcher.linkedItems.FetchExpiring(ctx, basePath, func(ctx context.Context) (interface{}, error) {
+		return content_store.GetContentItem(ctx, basePath, fetcher.apiRequest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	noteExpiry(ctx, expires)
+
+	return item.(*content_store.ContentItem), nil
+}
+
+func (fetcher *Fetcher) Needs(ctx context.Context, ids []string) ([]*need_api.Need, error) {
+	return fetcher.needClient.Needs(ctx, ids)
+}
+
+func (fetcher *Fetcher) need(ctx context.Context, id string) (*need_api.Need, error) {
+	need, expires, err := fetcher.needs.FetchExpiring(ctx, id, func(ctx context.Context) (interface{}, error) {
+		return fetcher.needClient.Need(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	noteExpiry(ctx, expires)
+
+	return need.(*need_api.Need), nil
+}
+
+// Performance fetches statistics for slug in the window chosen by query. The
+// default window moves on each day, so the cached statistics for it do too.
+func (fetcher *Fetcher) Performance(ctx context.Context, slug string, is_multipart bool,
+	query performance_platform.QueryOptions) (*performance_platform.Statistics, error) {
+	key := fmt.Sprintf("%s|%t|%s", slug, is_multipart, query)
+
+	statistics, expires, err := fetcher.statistics.FetchExpiring(ctx, key, func(ctx context.Context) (interface{}, error) {
+		ppOptions := []performanceclient.Option{performance_platform.WithContext(ctx)}
+		if fetcher.config.PerformanceAPITimeout > 0 {
+			ppOptions = append(ppOptions, performanceclient.MaxElapsedTime(fetcher.config.PerformanceAPITimeout))
+		}
+		ppClient := performanceclient.NewDataClient(fetcher.performanceAPI, logging, ppOptions...)
+
+		statistics, err := performance_platform.SlugStatistics(ctx, ppClient, slug, is_multipart, query)
+		if err != nil || !is_multipart {
+			return statistics, err
+		}
+		return statistics.Beneath(slug), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	noteExpiry(ctx, expires)
+
+	return statistics.(*performance_platform.Statistics), nil
+}
+
+// artefactPerformance is Performance for the item at slug with the search
+// terms chosen by options, broken down by part if it has more than one page.
+func (fetcher *Fetcher) artefactPerformance(ctx context.Context, slug string, artefact *content.Artefact,
+	options InfoOptions) (*performance_platform.Statistics, error) {
+	performanceStart := time.Now()
+	defer func() { statsDTiming("performance", performanceStart, time.Now()) }()
+
+	// The statistics for a part are for its own path, not the whole
+	// artefact's.
+	part := artefact.CurrentPart()
+	is_multipart := part == nil && ((len(artefact.Details.Parts) != 0) || (artefact.Format == "smart_answer"))
+	performance, err := fetcher.Performance(ctx, slug, is_multipart, options.Statistics)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.AggregateTranslations {
+		if performance, err = fetcher.translationsPerformance(ctx, slug, is_multipart, artefact,
+			performance, options); err != nil {
+			return nil, err
+		}
+	}
+
+	performance = performance.WithSearchTerms(options.SearchTerms)
+	if is_multipart {
+		basePaths := []string{slug}
+		if options.AggregateTranslations {
+			for _, translation := range artefact.AvailableTranslations {
+				basePaths = append(basePaths, translation.BasePath)
+			}
+		}
+		performance = performance.WithParts(basePaths, performanceParts(artefact))
+	}
+	return performance, nil
+}
+
+// translationsPerformance combines performance, the statistics for slug,
+// with those for every other translation of artefact, or for the same part
+// of them if a part was asked for.
+func (fetcher *Fetcher) translationsPerformance(ctx context.Context, slug string, is_multipart bool,
+	artefact *content.Artefact, performance *performance_platform.Statistics,
+	options InfoOptions) (*performance_platform.Statistics, error) {
+	// Each translation's statistics have their own place, so that they're
+	// combined in the same order however quickly they're fetched.
+	translations := make([]*performance_platform.Statistics, len(artefact.AvailableTranslations))
+
+	group, ctx := errgroup.WithContext(ctx)
+	part := artefact.CurrentPart()
+	for i, translation := range artefact.AvailableTranslations {
+		i, basePath := i, translation.BasePath
+		if part != nil {
+			basePath += "/" + part.Slug
+		}
+		if basePath == slug {
+			continue
+		}
+
+		group.Go(func() error {
+			statistics, err := fetcher.Performance(ctx, basePath, is_multipart, options.Statistics)
+			translations[i] = statistics
+			return err
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	all := []*performance_platform.Statistics{performance}
+	for _, statistics := range translations {
+		if statistics != nil {
+			all = append(all, statistics)
+		}
+	}
+	return performance_platform.Combine(all...), nil
+}
+
+func performanceParts(artefact *content.Artefact) []performance_platform.Part {
+	parts := make([]performance_platform.Part, len(artefact.Details.Parts))
+	for i, part := range artefact.Details.Parts {
+		parts[i] = performance_platform.Part{Slug: part.Slug, Title: part.Title, WebURL: part.WebURL}
+	}
+	return parts
+}
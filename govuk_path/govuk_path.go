@@ -0,0 +1,112 @@
+// Package govuk_path turns the paths and URLs clients ask about, however
+// they're written, into the base paths that content-store and the
+// Performance Platform know pages by.
+package govuk_path
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/alphagov/plek/go"
+)
+
+// hosts are the hosts of full URLs that are accepted, as well as that of
+// the website root.
+var hosts = map[string]bool{
+	"www.gov.uk": true,
+	"gov.uk":     true,
+}
+
+// Error is returned for a path or URL that can't be looked up.
+type Error struct {
+	Path   string
+	Reason string
+}
+
+func (e Error) Error() string {
+	return "path " + e.Path + " " + e.Reason
+}
+
+// Normalise reads the base path from raw, which is a path as it appears in
+// a URL, percent-encoded, or a full GOV.UK URL. Any query or fragment is
+// dropped, and the result is Clean.
+func Normalise(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	if colon := strings.Index(raw, "://"); colon > 0 && !strings.ContainsAny(raw[:colon], "/?#") {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return "", Error{raw, "is not a valid URL"}
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return "", Error{raw, "is not a web address"}
+		}
+		if !isGOVUK(parsed.Host) {
+			return "", Error{raw, "is not on GOV.UK"}
+		}
+		return Clean(parsed.Path)
+	}
+
+	path := raw
+	if end := strings.IndexAny(path, "?#"); end >= 0 {
+		path = path[:end]
+	}
+
+	// Any leading slashes are trimmed first, since a path starting "//"
+	// would be read as a host.
+	parsed, err := url.Parse("/" + strings.TrimLeft(path, "/"))
+	if err != nil {
+		return "", Error{raw, "is not a valid path"}
+	}
+	return Clean(parsed.Path)
+}
+
+// Clean tidies up a path that has already been decoded: it has one leading
+// slash, no trailing slash and no empty segments. A path with "." or ".."
+// segments, which could escape where it's looked up, or with control
+// characters, is an Error.
+func Clean(path string) (string, error) {
+	segments := strings.Split(path, "/")
+	kept := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		switch segment {
+		case "":
+			continue
+		case ".", "..":
+			return "", Error{path, "has a relative segment"}
+		}
+
+		for _, r := range segment {
+			if r < 0x20 || r == 0x7f {
+				return "", Error{path, "has a control character"}
+			}
+		}
+		kept = append(kept, segment)
+	}
+
+	return "/" + strings.Join(kept, "/"), nil
+}
+
+// Escape is the Clean form of path, percent-encoded for use in a URL, so
+// that characters such as "?" stay part of the path.
+func Escape(path string) (string, error) {
+	cleaned, err := Clean(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Path: cleaned}).EscapedPath(), nil
+}
+
+func isGOVUK(host string) bool {
+	if hosts[strings.ToLower(host)] {
+		return true
+	}
+
+	webroot, err := plek.WebsiteRoot()
+	if err != nil {
+		return false
+	}
+	root, err := url.Parse(webroot)
+	return err == nil && root.Host != "" && strings.EqualFold(root.Host, host)
+}
@@ -0,0 +1,13 @@
+package govuk_path_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGovukPath(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GOV.UK Path Suite")
+}
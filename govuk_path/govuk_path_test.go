@@ -0,0 +1,70 @@
+package govuk_path_test
+
+import (
+	"os"
+
+	. "github.com/alphagov/metadata-api/govuk_path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Normalise", func() {
+	for _, c := range []struct{ raw, path string }{
+		{"/vat-rates", "/vat-rates"},
+		{"vat-rates", "/vat-rates"},
+		{"/vat-rates/", "/vat-rates"},
+		{"//government//publications///x", "/government/publications/x"},
+		{"/vat-rates?tab=1#rates", "/vat-rates"},
+		{" /vat-rates\n", "/vat-rates"},
+		{"/caf%C3%A9/a%20b", "/café/a b"},
+		{"/what%3F", "/what?"},
+		{"https://www.gov.uk/vat-rates/?x=1", "/vat-rates"},
+		{"HTTP://GOV.UK/vat-rates", "/vat-rates"},
+		{"https://www.gov.uk", "/"},
+		{"", "/"},
+	} {
+		c := c
+		It("reads "+c.raw+" as "+c.path, func() {
+			path, err := Normalise(c.raw)
+			Expect(err).To(BeNil())
+			Expect(path).To(Equal(c.path))
+		})
+	}
+
+	for _, raw := range []string{
+		"/../admin",
+		"/government/./publications",
+		"/government/%2E%2E/%2e%2e/admin",
+		"/a%00b",
+		"/a%zz",
+		"https://www.example.com/vat-rates",
+		"ftp://www.gov.uk/vat-rates",
+	} {
+		raw := raw
+		It("rejects "+raw, func() {
+			_, err := Normalise(raw)
+			Expect(err).To(BeAssignableToTypeOf(Error{}))
+		})
+	}
+
+	It("accepts URLs on the website root", func() {
+		os.Setenv("GOVUK_WEBSITE_ROOT", "http://www.dev.gov.uk")
+		defer os.Unsetenv("GOVUK_WEBSITE_ROOT")
+
+		Expect(Normalise("http://www.dev.gov.uk/vat-rates")).To(Equal("/vat-rates"))
+	})
+})
+
+var _ = Describe("Escape", func() {
+	It("keeps characters that mean something in URLs part of the path", func() {
+		Expect(Escape("/what?/a b#c")).To(Equal("/what%3F/a%20b%23c"))
+	})
+
+	It("cleans the path first", func() {
+		Expect(Escape("known/")).To(Equal("/known"))
+
+		_, err := Escape("/a/../../admin")
+		Expect(err).To(Equal(Error{Path: "/a/../../admin", Reason: "has a relative segment"}))
+	})
+})
@@ -1,13 +1,17 @@
 package main_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	. "github.com/alphagov/metadata-api"
+	"github.com/alphagov/metadata-api/content"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -19,10 +23,21 @@ type stubbedJSONRequest struct {
 	Response *string
 }
 
-func (apiRequest stubbedJSONRequest) GetJSON(url string, bearerToken string) (string, error) {
+func (apiRequest stubbedJSONRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
 	return *apiRequest.Response, nil
 }
 
+// countingJSONRequest counts the requests made through a JSONRequest.
+type countingJSONRequest struct {
+	content.JSONRequest
+	Count *int32
+}
+
+func (apiRequest countingJSONRequest) GetJSON(ctx context.Context, url string, bearerToken string) (string, error) {
+	atomic.AddInt32(apiRequest.Count, 1)
+	return apiRequest.JSONRequest.GetJSON(ctx, url, bearerToken)
+}
+
 var _ = Describe("Info", func() {
 	var (
 		contentStoreResponsePointer, needAPIResponsePointer, pageviewsResponsePointer,
@@ -30,6 +45,8 @@ var _ = Describe("Info", func() {
 
 		testServer, testNeedAPI, testPerformanceAPI *httptest.Server
 
+		needAPIRequests, performanceAPIRequests int32
+
 		testApiRequest stubbedJSONRequest
 
 		config = &Config{
@@ -52,7 +69,10 @@ var _ = Describe("Info", func() {
 		problemReportsResponsePointer = &problemReportsResponse
 		termsResponsePointer = &termsResponse
 
+		needAPIRequests, performanceAPIRequests = 0, 0
+
 		testNeedAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&needAPIRequests, 1)
 			if r.Header.Get("Authorization") != "Bearer "+config.BearerTokenNeedAPI {
 				w.WriteHeader(http.StatusUnauthorized)
 				fmt.Fprintln(w, "Not authorised!")
@@ -64,6 +84,7 @@ var _ = Describe("Info", func() {
 		})
 
 		testPerformanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&performanceAPIRequests, 1)
 			if strings.Contains(r.URL.Path, "page-statistics") {
 				w.WriteHeader(http.StatusOK)
 				fmt.Fprintln(w, *pageviewsResponsePointer)
@@ -93,6 +114,7 @@ var _ = Describe("Info", func() {
 	AfterEach(func() {
 		testServer.Close()
 		testNeedAPI.Close()
+		testPerformanceAPI.Close()
 	})
 
 	Describe("no slug provided", func() {
@@ -117,6 +139,478 @@ var _ = Describe("Info", func() {
 		})
 	})
 
+	Describe("choosing the statistics window", func() {
+		It("rejects an invalid period", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug?period=fortnight")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`period must be one of day, week or month`))
+		})
+
+		It("asks the performance platform for the window given", func() {
+			*contentStoreResponsePointer = `{"base_path": "/dummy-slug", "content_id": "id", "title": "Dummy", "document_type": "answer"}`
+
+			queries := make(chan string, 4)
+			windowPerformanceAPI := testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+				queries <- r.URL.RawQuery
+				fmt.Fprintln(w, `{"data":[]}`)
+			})
+			defer windowPerformanceAPI.Close()
+			windowServer := testHandlerServer(InfoHandler(testNeedAPI.URL, windowPerformanceAPI.URL,
+				testApiRequest, &Config{}))
+			defer windowServer.Close()
+
+			response, err := getSlug(windowServer.URL, "dummy-slug?from=2017-01-02&to=2017-01-15&period=week")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			query := <-queries
+			Expect(query).To(ContainSubstring("start_at=2017-01-02T00%3A00%3A00Z"))
+			Expect(query).To(ContainSubstring("end_at=2017-01-16T00%3A00%3A00Z"))
+			Expect(query).To(ContainSubstring("period=week"))
+		})
+	})
+
+	Describe("choosing search terms", func() {
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			termsResponseBytes, _ := ioutil.ReadFile("fixtures/performance_platform_terms_response.json")
+
+			*contentStoreResponsePointer = string(contentStoreResponseBytes)
+			*termsResponsePointer = string(termsResponseBytes)
+		})
+
+		It("limits them to search_terms_limit", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug?search_terms_limit=1")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			body, _ := readResponseBody(response)
+			Expect(body).To(MatchRegexp(`"search_terms":\[{"Keyword":"employer access","TotalSearches":126,` +
+				`"PercentageOfTotal":38.89,"Searches":\[[^\]]*\]}\]`))
+		})
+
+		It("rejects an invalid limit", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug?search_terms_limit=1000")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("fetching a content item that can't be parsed", func() {
+		BeforeEach(func() {
+			*contentStoreResponsePointer = `{"base_path": "/dummy-slug", "title": "Dummy"}`
+		})
+
+		It("returns a 502 naming the offending field", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`content item /dummy-slug: field \"content_id\" is missing`))
+		})
+	})
+
+	Describe("an upstream that is slower than its timeout", func() {
+		var slowServer, slowNeedAPI *httptest.Server
+
+		BeforeEach(func() {
+			*contentStoreResponsePointer = `{"base_path": "/dummy-slug", "content_id": "id", "title": "Dummy", "document_type": "answer", "need_ids": ["100019"]}`
+
+			slowNeedAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+
+			slowServer = testHandlerServer(InfoHandler(slowNeedAPI.URL, testPerformanceAPI.URL, testApiRequest, &Config{
+				NeedAPITimeout: 50 * time.Millisecond,
+			}))
+		})
+
+		AfterEach(func() {
+			slowServer.Close()
+			slowNeedAPI.Close()
+		})
+
+		It("gives up on it and reports the section as timed out", func() {
+			start := time.Now()
+			response, err := getSlug(slowServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`"needs":null`))
+			Expect(body).To(ContainSubstring(`"errors":[{"section":"needs","code":"timeout"`))
+		})
+
+		It("returns a 504 in strict mode", func() {
+			response, err := getSlug(slowServer.URL, "dummy-slug?strict=true")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusGatewayTimeout))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`"status":"Need: `))
+		})
+	})
+
+	Describe("an upstream that fails", func() {
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			needAPIResponseBytes, _ := ioutil.ReadFile("fixtures/need_api_response.json")
+
+			*contentStoreResponsePointer = strings.Replace(string(contentStoreResponseBytes),
+				`"need_ids": []`, `"need_ids": ["100019"]`, 1)
+			*needAPIResponsePointer = string(needAPIResponseBytes)
+			*pageviewsResponsePointer = `{"status": "error", "message": "Backdrop is down"}`
+		})
+
+		It("returns the sections that succeeded and lists the ones that failed", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`"title":"Volunteer"`))
+			Expect(body).To(ContainSubstring(`"needs":[{"id":100019`))
+			Expect(body).To(ContainSubstring(`"performance":null`))
+			Expect(body).To(ContainSubstring(
+				`"errors":[{"section":"performance","code":"upstream_error","message":"page-statistics: Backdrop is down"}]`))
+			Expect(body).To(ContainSubstring(`"_response_info":{"status":"partial"}`))
+		})
+
+		It("fails the whole request in strict mode", func() {
+			response, err := getSlug(testServer.URL, "dummy-slug?strict=true")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(Equal(`{"artefact":null,"needs":null,"performance":null,` +
+				`"_response_info":{"status":"Performance: page-statistics: Backdrop is down"}}`))
+		})
+
+		It("reports needs that Need API won't authorise as unauthorized, rather than empty", func() {
+			unauthorisedServer := testHandlerServer(InfoHandler(testNeedAPI.URL, testPerformanceAPI.URL,
+				testApiRequest, &Config{BearerTokenNeedAPI: "wrong"}))
+			defer unauthorisedServer.Close()
+
+			response, err := getSlug(unauthorisedServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+
+			body, err := readResponseBody(response)
+			Expect(err).To(BeNil())
+			Expect(body).To(ContainSubstring(`"needs":null`))
+			Expect(body).To(ContainSubstring(`{"section":"needs","code":"unauthorized"`))
+		})
+	})
+
+	Describe("with caching enabled", func() {
+		var (
+			cachingServer        *httptest.Server
+			contentStoreRequests int32
+		)
+
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			needAPIResponseBytes, _ := ioutil.ReadFile("fixtures/need_api_response.json")
+
+			*contentStoreResponsePointer = strings.Replace(string(contentStoreResponseBytes),
+				`"need_ids": []`, `"need_ids": ["100019", "100019"]`, 1)
+			*needAPIResponsePointer = string(needAPIResponseBytes)
+			contentStoreRequests = 0
+
+			cachingServer = testHandlerServer(InfoHandler(testNeedAPI.URL, testPerformanceAPI.URL,
+				countingJSONRequest{testApiRequest, &contentStoreRequests},
+				&Config{
+					BearerTokenNeedAPI: config.BearerTokenNeedAPI,
+					ArtefactCacheTTL:   time.Minute,
+					NeedCacheTTL:       time.Minute,
+					StatisticsCacheTTL: time.Minute,
+					CacheSize:          10,
+				}))
+		})
+
+		AfterEach(func() {
+			cachingServer.Close()
+		})
+
+		It("serves repeated requests for a slug without calling upstream again", func() {
+			for i := 0; i < 3; i++ {
+				response, err := getSlug(cachingServer.URL, "dummy-slug")
+				Expect(err).To(BeNil())
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+				body, err := readResponseBody(response)
+				Expect(err).To(BeNil())
+				Expect(body).To(ContainSubstring(`"needs":[{"id":100019`))
+				Expect(body).To(ContainSubstring(`"_response_info":{"status":"ok"}`))
+			}
+
+			Expect(atomic.LoadInt32(&contentStoreRequests)).To(Equal(int32(1)))
+			Expect(atomic.LoadInt32(&needAPIRequests)).To(Equal(int32(1)))
+			Expect(atomic.LoadInt32(&performanceAPIRequests)).To(Equal(int32(4)))
+		})
+	})
+
+	Describe("conditional requests", func() {
+		var conditionalServer *httptest.Server
+
+		get := func(slug string, header http.Header) *http.Response {
+			request, _ := http.NewRequest("GET", conditionalServer.URL+"/info/"+slug, nil)
+			for key, values := range header {
+				request.Header[key] = values
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).To(BeNil())
+			return response
+		}
+
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			pageviewsResponseBytes, _ := ioutil.ReadFile("fixtures/performance_platform_pageviews_response.json")
+
+			*contentStoreResponsePointer = string(contentStoreResponseBytes)
+			*pageviewsResponsePointer = string(pageviewsResponseBytes)
+
+			conditionalServer = testHandlerServer(InfoHandler(testNeedAPI.URL, testPerformanceAPI.URL,
+				testApiRequest, &Config{
+					ArtefactCacheTTL:   time.Hour,
+					NeedCacheTTL:       time.Hour,
+					StatisticsCacheTTL: time.Minute,
+					CacheSize:          10,
+				}))
+		})
+
+		AfterEach(func() {
+			conditionalServer.Close()
+		})
+
+		It("sets validators and a max-age no longer than the freshest section allows", func() {
+			response := get("dummy-slug", nil)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Header.Get("ETag")).To(MatchRegexp(`^"[0-9a-f]{40}"$`))
+			Expect(response.Header.Get("Cache-Control")).To(MatchRegexp(`^public, max-age=\d+$`))
+
+			var maxAge int
+			fmt.Sscanf(response.Header.Get("Cache-Control"), "public, max-age=%d", &maxAge)
+			Expect(maxAge).To(BeNumerically("<=", 60))
+
+			// The statistics run up to today, which is after the fixture's
+			// public_updated_at.
+			lastModified, err := http.ParseTime(response.Header.Get("Last-Modified"))
+			Expect(err).To(BeNil())
+			Expect(lastModified.After(time.Date(2017, 3, 23, 12, 5, 3, 0, time.UTC))).To(BeTrue())
+
+			Expect(get("dummy-slug", nil).Header.Get("ETag")).To(Equal(response.Header.Get("ETag")))
+		})
+
+		It("counts the max-age down from when the cached sections were fetched", func() {
+			shortServer := testHandlerServer(InfoHandler(testNeedAPI.URL, testPerformanceAPI.URL,
+				testApiRequest, &Config{
+					ArtefactCacheTTL:   time.Hour,
+					StatisticsCacheTTL: 2 * time.Second,
+					CacheSize:          10,
+				}))
+			defer shortServer.Close()
+
+			maxAge := func() int {
+				response, err := getSlug(shortServer.URL, "dummy-slug")
+				Expect(err).To(BeNil())
+
+				var seconds int
+				fmt.Sscanf(response.Header.Get("Cache-Control"), "public, max-age=%d", &seconds)
+				return seconds
+			}
+
+			first := maxAge()
+			Expect(first).To(BeNumerically("<=", 2))
+			time.Sleep(time.Second)
+			Expect(maxAge()).To(BeNumerically("<", first))
+		})
+
+		It("uses public_updated_at and no max-age when statistics are missing", func() {
+			*pageviewsResponsePointer = `{"status": "error", "message": "Backdrop is down"}`
+
+			response := get("dummy-slug", nil)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Header.Get("Last-Modified")).To(Equal("Thu, 23 Mar 2017 12:05:03 GMT"))
+			Expect(response.Header.Get("Cache-Control")).To(Equal("public, max-age=0"))
+		})
+
+		It("responds 304 when If-None-Match matches", func() {
+			etag := get("dummy-slug", nil).Header.Get("ETag")
+
+			response := get("dummy-slug", http.Header{"If-None-Match": {`"other", W/` + etag}})
+			Expect(response.StatusCode).To(Equal(http.StatusNotModified))
+			Expect(response.Header.Get("ETag")).To(Equal(etag))
+			body, _ := readResponseBody(response)
+			Expect(body).To(BeEmpty())
+
+			response = get("dummy-slug", http.Header{"If-None-Match": {`"other"`}})
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("responds 304 when not modified since If-Modified-Since", func() {
+			lastModified := get("dummy-slug", nil).Header.Get("Last-Modified")
+
+			response := get("dummy-slug", http.Header{"If-Modified-Since": {lastModified}})
+			Expect(response.StatusCode).To(Equal(http.StatusNotModified))
+
+			response = get("dummy-slug", http.Header{"If-Modified-Since": {"Thu, 23 Mar 2017 12:05:02 GMT"}})
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response = get("dummy-slug", http.Header{
+				"If-Modified-Since": {lastModified},
+				"If-None-Match":     {`"other"`},
+			})
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("anomalies", func() {
+		var anomaliesServer *httptest.Server
+
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			pageviewsResponseBytes, _ := ioutil.ReadFile("fixtures/performance_platform_pageviews_response.json")
+			problemReportsResponseBytes, _ := ioutil.ReadFile("fixtures/performance_platform_problem_reports_response.json")
+
+			*contentStoreResponsePointer = string(contentStoreResponseBytes)
+			*pageviewsResponsePointer = string(pageviewsResponseBytes)
+			*problemReportsResponsePointer = string(problemReportsResponseBytes)
+
+			fetcher := NewFetcher(testNeedAPI.URL, testPerformanceAPI.URL, testApiRequest, config)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/info/", FetcherInfoHandler(fetcher))
+			mux.HandleFunc("/anomalies/", AnomaliesHandler(fetcher))
+			anomaliesServer = httptest.NewServer(mux)
+		})
+
+		AfterEach(func() {
+			anomaliesServer.Close()
+		})
+
+		It("are included in /info when there are some", func() {
+			response, err := getSlug(anomaliesServer.URL, "dummy-slug?anomaly_window=2")
+			Expect(err).To(BeNil())
+			body, _ := readResponseBody(response)
+			Expect(body).To(ContainSubstring(`"anomalies":[{"series":"problem_reports","timestamp":"2014-07-27T00:00:00Z"`))
+
+			response, err = getSlug(anomaliesServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			body, _ = readResponseBody(response)
+			Expect(body).ToNot(ContainSubstring(`"anomalies"`))
+		})
+
+		It("are served on their own", func() {
+			response, err := http.Get(anomaliesServer.URL + "/anomalies/dummy-slug?anomaly_method=mad&anomaly_window=2")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			body, _ := readResponseBody(response)
+			Expect(body).To(Equal(`{"anomalies":[{"series":"problem_reports","timestamp":"2014-07-27T00:00:00Z",` +
+				`"value":16,"baseline":0,"score":16,"direction":"spike"}],"_response_info":{"status":"ok"}}`))
+			Expect(atomic.LoadInt32(&needAPIRequests)).To(Equal(int32(0)))
+		})
+
+		It("reject invalid options", func() {
+			response, err := http.Get(anomaliesServer.URL + "/anomalies/dummy-slug?anomaly_method=guess")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("are not found for a missing item", func() {
+			response, err := http.Get(anomaliesServer.URL + "/anomalies/")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("serving stale metadata", func() {
+		var (
+			staleServer          *httptest.Server
+			staleFetcher         *Fetcher
+			contentStoreRequests int32
+		)
+
+		BeforeEach(func() {
+			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
+			pageviewsResponseBytes, _ := ioutil.ReadFile("fixtures/performance_platform_pageviews_response.json")
+
+			*contentStoreResponsePointer = string(contentStoreResponseBytes)
+			*pageviewsResponsePointer = string(pageviewsResponseBytes)
+
+			contentStoreRequests = 0
+			staleFetcher = NewFetcher(testNeedAPI.URL, testPerformanceAPI.URL,
+				countingJSONRequest{testApiRequest, &contentStoreRequests},
+				&Config{StaleTTL: time.Hour, StaleRefreshInterval: 100 * time.Millisecond, CacheSize: 10})
+			staleServer = testHandlerServer(FetcherInfoHandler(staleFetcher))
+		})
+
+		AfterEach(func() {
+			staleServer.Close()
+			staleFetcher.WaitForRefreshes()
+		})
+
+		It("serves the last good metadata while an upstream fails, refreshing it in the background", func() {
+			response, err := getSlug(staleServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			body, _ := readResponseBody(response)
+			Expect(body).To(ContainSubstring(`"_response_info":{"status":"ok"}`))
+
+			*pageviewsResponsePointer = `{"status": "error", "message": "Backdrop is down"}`
+
+			response, err = getSlug(staleServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			body, _ = readResponseBody(response)
+			Expect(body).To(ContainSubstring(`"page_views":[{"path":"/dummy-slug"`))
+			Expect(body).To(MatchRegexp(`"_response_info":{"status":"ok","stale":true,"fetched_at":"[^"]+"}`))
+			Expect(body).ToNot(ContainSubstring(`"errors"`))
+
+			// One fetch for each request and a single one for the refresh,
+			// however many stale responses are served while it's pending
+			getSlug(staleServer.URL, "dummy-slug")
+			staleFetcher.WaitForRefreshes()
+			Eventually(func() int32 {
+				return atomic.LoadInt32(&contentStoreRequests)
+			}).Should(Equal(int32(4)))
+		})
+
+		It("fails strict requests instead of serving stale metadata", func() {
+			getSlug(staleServer.URL, "dummy-slug")
+			*pageviewsResponsePointer = `{"status": "error", "message": "Backdrop is down"}`
+
+			response, err := getSlug(staleServer.URL, "dummy-slug?strict=true")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+			body, _ := readResponseBody(response)
+			Expect(body).ToNot(ContainSubstring(`"stale":true`))
+		})
+
+		It("doesn't serve stale metadata for content that no longer exists", func() {
+			getSlug(staleServer.URL, "dummy-slug")
+			placeholderBytes, _ := ioutil.ReadFile("fixtures/content_store_response_placeholder.json")
+			*contentStoreResponsePointer = string(placeholderBytes)
+
+			response, err := getSlug(staleServer.URL, "dummy-slug")
+			Expect(err).To(BeNil())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
 	Describe("fetching a valid slug from the content store", func() {
 		BeforeEach(func() {
 			contentStoreResponseBytes, _ := ioutil.ReadFile("fixtures/content_store_response.json")
@@ -1,21 +1,20 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/alphagov/performanceplatform-client-go"
 	"github.com/codegangsta/negroni"
 	"github.com/meatballhat/negroni-logrus"
 	"github.com/quipo/statsd"
 	"gopkg.in/unrolled/render.v1"
 
 	"github.com/alphagov/metadata-api/content"
-	"github.com/alphagov/metadata-api/content_store"
 	"github.com/alphagov/metadata-api/need_api"
-	"github.com/alphagov/metadata-api/performance_platform"
 	"github.com/alphagov/metadata-api/request"
 )
 
@@ -43,78 +42,149 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 func InfoHandler(needAPI, performanceAPI string,
 	apiRequest content.JSONRequest, config *Config) func(http.ResponseWriter, *http.Request) {
+	return FetcherInfoHandler(NewFetcher(needAPI, performanceAPI, apiRequest, config))
+}
+
+// FetcherInfoHandler is InfoHandler using an existing Fetcher, so that its
+// caches can be shared with other handlers. It serves the deprecated
+// unversioned /info, in whichever version's schema the client Accepts.
+func FetcherInfoHandler(fetcher *Fetcher) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var needs []*need_api.Need = make([]*need_api.Need, 0)
+		version := negotiateVersion(r)
+		deprecate(w, r, version)
+		serveInfo(w, r, fetcher, "/info", version)
+	}
+}
 
-		slug := r.URL.Path[len("/info"):]
+// VersionedInfoHandler serves /info in version's schema under its prefix,
+// such as /v2/info.
+func VersionedInfoHandler(fetcher *Fetcher, version Version) func(http.ResponseWriter, *http.Request) {
+	prefix := version.prefix() + "/info"
 
-		if len(slug) <= 1 || slug == "/" {
-			renderError(w, http.StatusNotFound, "not found")
-			return
-		}
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveInfo(w, r, fetcher, prefix, version)
+	}
+}
 
-		artefactStart := time.Now()
-		statsDTiming("artefact", artefactStart, time.Now())
-		artefact, err := content_store.GetArtefact(slug, apiRequest)
-		if err != nil {
-			if err == request.NotFoundError {
-				renderError(w, http.StatusNotFound, err.Error())
-				return
-			}
+// requestedSlug is the path or GOV.UK URL that r, to an endpoint at prefix,
+// asks about: the url parameter if it's given, otherwise the rest of the
+// path as the client wrote it.
+func requestedSlug(r *http.Request, prefix string) string {
+	if url := r.URL.Query().Get("url"); url != "" {
+		return url
+	}
+	return strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+}
 
-			renderError(w, http.StatusInternalServerError, "Artefact: "+err.Error())
-			return
-		}
+func serveInfo(w http.ResponseWriter, r *http.Request, fetcher *Fetcher, prefix string, version Version) {
+	slug := requestedSlug(r, prefix)
 
-		needStart := time.Now()
-		for _, needID := range artefact.Details.NeedIDs {
-			need, err := need_api.FetchNeed(needAPI, config.BearerTokenNeedAPI, needID)
-			if err != nil {
-				renderError(w, http.StatusInternalServerError, "Need: "+err.Error())
-				return
-			}
-			needs = append(needs, need)
-		}
-		statsDTiming("needs", needStart, time.Now())
-
-		performanceStart := time.Now()
-		ppClient := performanceclient.NewDataClient(performanceAPI, logging)
-		is_multipart := (len(artefact.Details.Parts) != 0) || (artefact.Format == "smart_answer")
-		performance, err := performance_platform.SlugStatistics(ppClient, slug, is_multipart)
-		statsDTiming("performance", performanceStart, time.Now())
-		if err != nil {
-			renderError(w, http.StatusInternalServerError, "Performance: "+err.Error())
+	options, err := ParseInfoOptions(r.URL.Query())
+	if err != nil {
+		renderVersionError(w, version, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metadata, err := fetcher.Info(r.Context(), slug, options)
+	if err != nil {
+		metadataErr := err.(*MetadataError)
+		if metadataErr.Location != "" {
+			renderRedirect(w, r, prefix, version, metadataErr)
 			return
 		}
+		renderVersionError(w, version, metadataErr.Status, metadataErr.Message)
+		return
+	}
 
-		metadata := &Metadata{
-			Artefact:     artefact,
-			Needs:        needs,
-			Performance:  performance,
-			ResponseInfo: &ResponseInfo{Status: "ok"},
-		}
+	renderMetadata(w, r, fetcher, metadata, version)
+}
 
-		renderer.JSON(w, http.StatusOK, metadata)
+// NewRouter routes requests to the handlers for each endpoint, all sharing
+// fetcher.
+func NewRouter(fetcher *Fetcher) *http.ServeMux {
+	httpMux := http.NewServeMux()
+	httpMux.HandleFunc("/healthcheck", HealthCheckHandler)
+	httpMux.HandleFunc("/openapi.json", OpenAPIHandler())
+
+	httpMux.HandleFunc("/info", FetcherInfoHandler(fetcher))
+	httpMux.HandleFunc("/info/", FetcherInfoHandler(fetcher))
+	httpMux.HandleFunc("/info/batch", BatchInfoHandler(fetcher))
+	for _, version := range versions {
+		httpMux.HandleFunc(version.prefix()+"/info", VersionedInfoHandler(fetcher, version))
+		httpMux.HandleFunc(version.prefix()+"/info/", VersionedInfoHandler(fetcher, version))
+		httpMux.HandleFunc(version.prefix()+"/info/batch", VersionedBatchInfoHandler(fetcher, version))
 	}
+
+	httpMux.HandleFunc("/export", ExportHandler(fetcher))
+	httpMux.HandleFunc("/anomalies", AnomaliesHandler(fetcher))
+	httpMux.HandleFunc("/anomalies/", AnomaliesHandler(fetcher))
+
+	return httpMux
 }
 
 func main() {
 	config := InitConfig()
 
-	httpMux := http.NewServeMux()
-	httpMux.HandleFunc("/healthcheck", HealthCheckHandler)
-	httpMux.HandleFunc("/info/", InfoHandler(
-		needAPI, performanceAPI, apiRequest, config))
+	fetcher := NewFetcher(needAPI, performanceAPI, apiRequest, config)
 
 	middleware := negroni.New()
 	middleware.Use(loggingMiddleware)
-	middleware.UseHandler(httpMux)
+	middleware.UseHandler(NewRouter(fetcher))
 
 	middleware.Run(":" + port)
 }
 
 func renderError(w http.ResponseWriter, status int, errorString string) {
-	renderer.JSON(w, status, &Metadata{ResponseInfo: &ResponseInfo{Status: errorString}})
+	renderer.JSON(w, status, errorMetadata(errorString))
+}
+
+// errorMetadata is the body of an error response, with the error in place of
+// the status.
+func errorMetadata(errorString string) *Metadata {
+	return &Metadata{ResponseInfo: &ResponseInfo{Status: errorString}}
+}
+
+// upstreamErrorStatus is the status to respond with when an upstream call
+// made with ctx fails: 504 if it ran out of time, otherwise 500.
+func upstreamErrorStatus(ctx context.Context) int {
+	if ctx.Err() == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
+// withTimeout is context.WithTimeout, except that a timeout of zero leaves the
+// parent's deadline, if any, in charge.
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, timeout)
+}
+
+// upstreamFailed reports whether the result of Fetcher.Metadata is missing
+// anything because an upstream API failed, rather than because it doesn't
+// exist.
+func upstreamFailed(metadata *Metadata, err error) bool {
+	if err != nil {
+		return err.(*MetadataError).Status >= http.StatusInternalServerError
+	}
+	return len(metadata.Errors) > 0
+}
+
+func newSectionError(section Section, ctx context.Context, err error) *SectionError {
+	code := "upstream_error"
+	if ctx.Err() == context.DeadlineExceeded {
+		code = "timeout"
+	} else if _, ok := err.(need_api.NotFoundError); ok || err == request.NotFoundError {
+		code = "not_found"
+	} else if _, ok := err.(need_api.UnauthorizedError); ok {
+		code = "unauthorized"
+	}
+
+	return &SectionError{Section: section, Code: code, Message: err.Error()}
 }
 
 func getEnvDefault(key string, defaultVal string) string {
@@ -1,17 +1,106 @@
 package main
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/content"
 	"github.com/alphagov/metadata-api/need_api"
 	"github.com/alphagov/metadata-api/performance_platform"
 )
 
+type Section string
+
+const (
+	ArtefactSection    Section = "artefact"
+	NeedsSection       Section = "needs"
+	PerformanceSection Section = "performance"
+	LinksSection       Section = "links"
+)
+
+// SectionError explains why a section of Metadata is missing. Code is one of
+// "timeout", "not_found", "unauthorized" or "upstream_error".
+type SectionError struct {
+	Section Section `json:"section"`
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+}
+
+// strictError is the status and message to respond with when this section
+// fails and the client asked for all or nothing.
+func (sectionError *SectionError) strictError() (int, string) {
+	status := http.StatusInternalServerError
+	if sectionError.Code == "timeout" {
+		status = http.StatusGatewayTimeout
+	}
+
+	prefix := map[Section]string{NeedsSection: "Need: ", PerformanceSection: "Performance: ", LinksSection: "Links: "}
+	return status, prefix[sectionError.Section] + sectionError.Message
+}
+
 type ResponseInfo struct {
 	Status string `json:"status"`
+
+	// Stale is set when an upstream failed and the last complete response,
+	// fetched at FetchedAt, is being served in its place.
+	Stale     bool       `json:"stale,omitempty"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+
+	// Location is where content that has moved is now, and RedirectedFrom
+	// where it was requested from if the redirect was followed.
+	Location       string `json:"location,omitempty"`
+	RedirectedFrom string `json:"redirected_from,omitempty"`
+
+	// StatusCode is the HTTP status a path in a batch or export failed
+	// with, which /info would have responded with for it.
+	StatusCode int `json:"status_code,omitempty"`
 }
 
 type Metadata struct {
-	Artefact     interface{}                      `json:"artefact"`
-	Needs        []*need_api.Need                 `json:"needs"`
-	Performance  *performance_platform.Statistics `json:"performance"`
-	ResponseInfo *ResponseInfo                    `json:"_response_info"`
+	Artefact            interface{}                        `json:"artefact"`
+	Links               map[string][]*content.ExpandedLink `json:"links,omitempty"`
+	Needs               []*need_api.Need                   `json:"needs"`
+	Performance         *performance_platform.Statistics   `json:"performance"`
+	Anomalies           []analysis.Anomaly                 `json:"anomalies,omitempty"`
+	EmergingSearchTerms *analysis.EmergingSearchTerms      `json:"emerging_search_terms,omitempty"`
+	Errors              []*SectionError                    `json:"errors,omitempty"`
+	ResponseInfo        *ResponseInfo                      `json:"_response_info"`
+
+	// expires is the earliest that any cached value it was made from
+	// expires.
+	expires time.Time
+}
+
+// AddError records that a section couldn't be fetched. The response is then
+// only partial, which is reflected in its status.
+func (metadata *Metadata) AddError(sectionError *SectionError) {
+	metadata.Errors = append(metadata.Errors, sectionError)
+	metadata.ResponseInfo.Status = "partial"
+}
+
+// SectionOK reports whether section was fetched successfully.
+func (metadata *Metadata) SectionOK(section Section) bool {
+	for _, sectionError := range metadata.Errors {
+		if sectionError.Section == section {
+			return false
+		}
+	}
+	return true
+}
+
+// LastModified is the later of when the artefact was last published and the
+// end of the period its statistics cover, or the zero time if neither is
+// known. Needs carry no timestamp, so changes to them aren't reflected.
+func (metadata *Metadata) LastModified() time.Time {
+	var lastModified time.Time
+
+	if artefact, ok := metadata.Artefact.(*content.Artefact); ok && artefact.PublicUpdatedAt != nil {
+		lastModified = *artefact.PublicUpdatedAt
+	}
+	if metadata.Performance != nil && metadata.Performance.EndAt.After(lastModified) {
+		lastModified = metadata.Performance.EndAt
+	}
+
+	return lastModified
 }
@@ -1,6 +1,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -26,3 +27,25 @@ func readResponseBody(response *http.Response) (string, error) {
 
 	return strings.TrimSpace(string(body)), err
 }
+
+// readJSONResponse decodes the JSON object response has as its body.
+func readJSONResponse(response *http.Response) map[string]interface{} {
+	var result map[string]interface{}
+	body, _ := readResponseBody(response)
+	Expect(json.Unmarshal([]byte(body), &result)).To(Succeed())
+	return result
+}
+
+// getJSON gets url, decoding the JSON object it responds with.
+func getJSON(url string) (*http.Response, map[string]interface{}) {
+	response, err := http.Get(url)
+	Expect(err).To(BeNil())
+	return response, readJSONResponse(response)
+}
+
+// pageViewsTotal is the total page views in the summary of metadata's
+// performance.
+func pageViewsTotal(metadata map[string]interface{}) float64 {
+	summary := metadata["performance"].(map[string]interface{})["summary"].(map[string]interface{})
+	return summary["page_views"].(map[string]interface{})["total"].(float64)
+}
@@ -0,0 +1,126 @@
+package need_api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alphagov/metadata-api/request"
+)
+
+// NotFoundError is returned for a need that Need API doesn't have.
+type NotFoundError struct {
+	ID string
+}
+
+func (e NotFoundError) Error() string {
+	return "need " + e.ID + " not found"
+}
+
+// UnauthorizedError is returned when Need API refuses the bearer token.
+type UnauthorizedError struct {
+	StatusCode int
+}
+
+func (e UnauthorizedError) Error() string {
+	return fmt.Sprintf("not authorised by Need API (status %d)", e.StatusCode)
+}
+
+// UpstreamError is returned when Need API can't be reached, fails, or
+// responds with something that isn't a need. StatusCode is zero if there
+// was no response.
+type UpstreamError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e UpstreamError) Error() string {
+	if e.Err != nil {
+		return "Need API: " + e.Err.Error()
+	}
+	return fmt.Sprintf("Need API responded with status %d", e.StatusCode)
+}
+
+// Client fetches needs from the Need API at BaseURL, authenticating with
+// BearerToken. Batches of needs are fetched with at most Concurrency
+// requests in flight.
+type Client struct {
+	BaseURL     string
+	BearerToken string
+	Concurrency int
+
+	// HTTPClient makes the requests, or http.DefaultClient if it's nil.
+	HTTPClient *http.Client
+
+	// Fetch, if it's set, fetches each need of a batch in place of Need,
+	// such as from a cache that calls Need for the needs it doesn't have.
+	Fetch NeedFetcher
+}
+
+// NewClient is a Client whose requests share one http.Client, which times
+// them out after request.DefaultTimeout.
+func NewClient(baseURL, bearerToken string, concurrency int) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		BearerToken: bearerToken,
+		Concurrency: concurrency,
+		HTTPClient:  &http.Client{Timeout: request.DefaultTimeout},
+	}
+}
+
+// Need fetches the need with id. Any error is a NotFoundError,
+// UnauthorizedError or UpstreamError.
+func (c *Client) Need(ctx context.Context, id string) (*Need, error) {
+	// IDs are escaped so that they can only ever name a need.
+	escapedID := strings.Replace(url.QueryEscape(id), "+", "%20", -1)
+	req, err := http.NewRequest("GET", c.BaseURL+"/needs/"+escapedID, nil)
+	if err != nil {
+		return nil, UpstreamError{Err: err}
+	}
+	req.Header.Add("Authorization", "Bearer "+c.BearerToken)
+	req.Header.Add("Accept", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, UpstreamError{Err: err}
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return nil, NotFoundError{ID: id}
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return nil, UnauthorizedError{StatusCode: response.StatusCode}
+	case response.StatusCode != http.StatusOK:
+		return nil, UpstreamError{StatusCode: response.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, UpstreamError{StatusCode: response.StatusCode, Err: err}
+	}
+
+	need, err := ParseNeedResponse(body)
+	if err != nil {
+		return nil, UpstreamError{StatusCode: response.StatusCode, Err: err}
+	}
+	return need, nil
+}
+
+// Needs fetches each distinct need in ids, as FetchNeeds does, with Fetch
+// or Need.
+func (c *Client) Needs(ctx context.Context, ids []string) ([]*Need, error) {
+	fetch := c.Fetch
+	if fetch == nil {
+		fetch = c.Need
+	}
+	return FetchNeeds(ctx, ids, c.Concurrency, fetch)
+}
@@ -0,0 +1,136 @@
+package need_api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/alphagov/metadata-api/need_api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		client   *Client
+		mutex    sync.Mutex
+		requests []string
+	)
+
+	BeforeEach(func() {
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			requests = append(requests, r.URL.EscapedPath())
+			mutex.Unlock()
+
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintln(w, `{"_response_info": {"status": "unauthorised"}}`)
+				return
+			}
+
+			switch id := strings.TrimPrefix(r.URL.Path, "/needs/"); id {
+			case "404":
+				w.WriteHeader(http.StatusNotFound)
+			case "500":
+				w.WriteHeader(http.StatusInternalServerError)
+			case "html":
+				fmt.Fprintln(w, "<html></html>")
+			case "slow":
+				time.Sleep(50 * time.Millisecond)
+				fmt.Fprintln(w, `{"id": 1}`)
+			default:
+				fmt.Fprintf(w, `{"id": %s, "goal": "goal"}`, id)
+			}
+		}))
+		client = NewClient(server.URL+"/", "token", 2)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Need", func() {
+		It("fetches the need with the bearer token", func() {
+			need, err := client.Need(context.Background(), "100001")
+			Expect(err).To(BeNil())
+			Expect(need).To(Equal(&Need{ID: 100001, Goal: "goal"}))
+			Expect(requests).To(Equal([]string{"/needs/100001"}))
+		})
+
+		It("returns a NotFoundError for a need that doesn't exist", func() {
+			_, err := client.Need(context.Background(), "404")
+			Expect(err).To(Equal(NotFoundError{ID: "404"}))
+		})
+
+		It("returns an UnauthorizedError instead of parsing the response", func() {
+			need, err := NewClient(server.URL, "wrong", 2).Need(context.Background(), "100001")
+			Expect(err).To(Equal(UnauthorizedError{StatusCode: http.StatusUnauthorized}))
+			Expect(need).To(BeNil())
+		})
+
+		It("returns an UpstreamError for other statuses and unparseable responses", func() {
+			_, err := client.Need(context.Background(), "500")
+			Expect(err).To(Equal(UpstreamError{StatusCode: http.StatusInternalServerError}))
+
+			_, err = client.Need(context.Background(), "html")
+			Expect(err).To(BeAssignableToTypeOf(UpstreamError{}))
+			Expect(err.(UpstreamError).StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("returns an UpstreamError when Need API can't be reached in time", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := client.Need(ctx, "slow")
+			Expect(err).To(BeAssignableToTypeOf(UpstreamError{}))
+			Expect(err.(UpstreamError).StatusCode).To(Equal(0))
+		})
+
+		It("escapes IDs", func() {
+			client.Need(context.Background(), "../healthcheck")
+			Expect(requests).To(Equal([]string{"/needs/..%2Fhealthcheck"}))
+		})
+
+		It("falls back to the default HTTP client", func() {
+			client.HTTPClient = nil
+			need, err := client.Need(context.Background(), "1")
+			Expect(err).To(BeNil())
+			Expect(need).To(Equal(&Need{ID: 1, Goal: "goal"}))
+		})
+	})
+
+	Describe("Needs", func() {
+		It("fetches each need once, in order", func() {
+			needs, err := client.Needs(context.Background(), []string{"2", "1", "2"})
+			Expect(err).To(BeNil())
+			Expect(needs).To(Equal([]*Need{{ID: 2, Goal: "goal"}, {ID: 1, Goal: "goal"}}))
+			Expect(requests).To(HaveLen(2))
+		})
+
+		It("fails if any need can't be fetched", func() {
+			needs, err := client.Needs(context.Background(), []string{"1", "500"})
+			Expect(err).To(Equal(UpstreamError{StatusCode: http.StatusInternalServerError}))
+			Expect(needs).To(BeNil())
+		})
+
+		It("fetches each need with Fetch if it's set", func() {
+			client.Fetch = func(ctx context.Context, id string) (*Need, error) {
+				return &Need{Goal: "cached " + id}, nil
+			}
+
+			needs, err := client.Needs(context.Background(), []string{"1"})
+			Expect(err).To(BeNil())
+			Expect(needs).To(Equal([]*Need{{Goal: "cached 1"}}))
+			Expect(requests).To(BeEmpty())
+		})
+	})
+})
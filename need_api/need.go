@@ -1,9 +1,10 @@
 package need_api
 
 import (
+	"context"
 	"encoding/json"
 
-	"github.com/alphagov/metadata-api/request"
+	"github.com/alphagov/metadata-api/errgroup"
 )
 
 type Organisation struct {
@@ -49,21 +50,41 @@ func ParseNeedResponse(response []byte) (*Need, error) {
 	return need, nil
 }
 
-func FetchNeed(needAPI, bearerToken, id string) (*Need, error) {
-	needResponse, err := request.NewRequest(needAPI+"/needs/"+id, bearerToken)
-	if err != nil {
-		return nil, err
+// NeedFetcher fetches a single need by its ID, as Client.Need does.
+type NeedFetcher func(ctx context.Context, id string) (*Need, error)
+
+// FetchNeeds fetches each distinct need in ids, with at most concurrency
+// requests in flight, and returns them in the order their IDs first appear.
+func FetchNeeds(ctx context.Context, ids []string, concurrency int, fetch NeedFetcher) ([]*Need, error) {
+	uniqueIDs := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			uniqueIDs = append(uniqueIDs, id)
+		}
 	}
 
-	needBody, err := request.ReadResponseBody(needResponse)
-	if err != nil {
-		return nil, err
+	needs := make([]*Need, len(uniqueIDs))
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+
+	for i, id := range uniqueIDs {
+		i, id := i, id
+		group.Go(func() error {
+			need, err := fetch(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			needs[i] = need
+			return nil
+		})
 	}
 
-	need, err := ParseNeedResponse([]byte(needBody))
-	if err != nil {
+	if err := group.Wait(); err != nil {
 		return nil, err
 	}
 
-	return need, nil
+	return needs, nil
 }
@@ -1,6 +1,14 @@
 package need_api_test
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
 	. "github.com/alphagov/metadata-api/need_api"
 
 	. "github.com/onsi/ginkgo"
@@ -75,3 +83,75 @@ var _ = Describe("Need", func() {
 		})
 	})
 })
+
+var _ = Describe("FetchNeeds", func() {
+	var (
+		server              *httptest.Server
+		requests            map[string]int
+		running, maxRunning int
+		mutex               sync.Mutex
+	)
+
+	BeforeEach(func() {
+		requests = make(map[string]int)
+		running, maxRunning = 0, 0
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.URL.Path, "/needs/")
+
+			mutex.Lock()
+			requests[id]++
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+
+			if id == "404" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"id": %s}`, id)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fetchNeed := func(ctx context.Context, id string) (*Need, error) {
+		return NewClient(server.URL, "token", 2).Need(ctx, id)
+	}
+
+	It("returns needs in their original order without fetching any twice", func() {
+		needs, err := FetchNeeds(context.Background(),
+			[]string{"3", "1", "2", "1", "3", "4"}, 2, fetchNeed)
+		Expect(err).To(BeNil())
+
+		ids := make([]int, len(needs))
+		for i, need := range needs {
+			ids[i] = need.ID
+		}
+		Expect(ids).To(Equal([]int{3, 1, 2, 4}))
+		Expect(requests).To(Equal(map[string]int{"1": 1, "2": 1, "3": 1, "4": 1}))
+		Expect(maxRunning).To(Equal(2))
+	})
+
+	It("returns an empty list when there are no IDs", func() {
+		needs, err := FetchNeeds(context.Background(), nil, 2, fetchNeed)
+		Expect(err).To(BeNil())
+		Expect(needs).To(Equal([]*Need{}))
+	})
+
+	It("returns an error if any need can't be fetched", func() {
+		needs, err := FetchNeeds(context.Background(), []string{"1", "404"}, 2, fetchNeed)
+		Expect(err).To(Equal(NotFoundError{ID: "404"}))
+		Expect(needs).To(BeNil())
+	})
+})
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/schema"
+)
+
+const schemaPrefix = "#/components/schemas/"
+
+// OpenAPIDocument describes the API in OpenAPI 3.1, whose schemas are JSON
+// Schema.
+type OpenAPIDocument struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       map[string]string      `json:"info"`
+	Paths      map[string]interface{} `json:"paths"`
+	Components OpenAPIComponents      `json:"components"`
+}
+
+type OpenAPIComponents struct {
+	Schemas schema.Definitions `json:"schemas"`
+}
+
+type object map[string]interface{}
+
+// infoParameters are the query parameters read by ParseInfoOptions.
+var infoParameters = []object{
+	queryParameter("url", "uri", "A GOV.UK URL or path to use instead of the one in the request path."),
+	queryParameter("from", "date", "The first day of the statistics window, as YYYY-MM-DD."),
+	queryParameter("to", "date", "The last day of the statistics window, as YYYY-MM-DD."),
+	queryParameter("period", "", "The period each statistic covers: day, week or month."),
+	queryParameter("search_terms_limit", "", "How many search terms to show, from 1 to 100."),
+	queryParameter("exclude_stop_words", "", "Whether to leave words such as \"the\" out of search terms."),
+	queryParameter("anomaly_method", "", "How to detect anomalies: zscore or mad."),
+	queryParameter("anomaly_threshold", "", "The score beyond which a value is anomalous."),
+	queryParameter("anomaly_window", "", "How many days before a value it's compared with."),
+	queryParameter("expand", "", "Which links to resolve: organisations, taxons, mainstream_browse_pages or parent."),
+	queryParameter("expand_depth", "", "How many links away to resolve links, from 1 to 3."),
+	queryParameter("follow_redirects", "", "Whether to respond for where moved content is now, rather than redirecting."),
+	queryParameter("locale", "", "The locale of the translation to describe, such as cy."),
+	queryParameter("aggregate_translations", "", "Whether to add together the statistics for every translation."),
+	queryParameter("strict", "", "Whether to fail rather than leave out sections that couldn't be fetched."),
+}
+
+// NewOpenAPIDocument describes /info and batch requests for each version,
+// with schemas derived from the types their responses are encoded from.
+func NewOpenAPIDocument() *OpenAPIDocument {
+	generator := schema.NewGenerator(schemaPrefix)
+	generator.Concrete(Metadata{}, "artefact", &ArtefactV1{})
+	generator.Concrete(MetadataV2{}, "artefact", &content.Artefact{})
+
+	bodies := map[Version]*schema.Schema{
+		V1: generator.Reflect(Metadata{}),
+		V2: generator.Reflect(MetadataV2{}),
+	}
+
+	paths := make(map[string]interface{})
+	for _, version := range versions {
+		body := bodies[version]
+		responses := object{version.responseMediaType(): object{"schema": body}}
+
+		paths[version.prefix()+"/info/{path}"] = object{"get": infoOperation(responses, false)}
+		paths[version.prefix()+"/info/batch"] = object{"post": batchOperation(version, body)}
+	}
+
+	unversioned := object{}
+	for _, version := range versions {
+		unversioned[version.mediaType()] = object{"schema": bodies[version]}
+	}
+	unversioned[V1.responseMediaType()] = object{"schema": bodies[V1]}
+	paths["/info/{path}"] = object{"get": infoOperation(unversioned, true)}
+
+	return &OpenAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: map[string]string{
+			"title":   "Metadata API",
+			"version": "2",
+		},
+		Paths:      paths,
+		Components: OpenAPIComponents{Schemas: generator.Definitions},
+	}
+}
+
+func infoOperation(responses object, deprecated bool) object {
+	parameters := []object{{
+		"name":        "path",
+		"in":          "path",
+		"required":    true,
+		"description": "The path of the content on GOV.UK, which may include slashes.",
+		"schema":      object{"type": "string"},
+	}}
+
+	return object{
+		"summary":    "Metadata about a page on GOV.UK",
+		"deprecated": deprecated,
+		"parameters": append(parameters, infoParameters...),
+		"responses": object{
+			"200": object{"description": "The page's metadata", "content": responses},
+			"304": object{"description": "The client's copy is still current"},
+			"3XX": object{
+				"description": "The page has moved to the Location header: 301 in v1, 308 in v2",
+				"content":     responses,
+			},
+			"410":     object{"description": "The page has been removed from GOV.UK", "content": responses},
+			"default": object{"description": "An error, described by the status in the response info", "content": responses},
+		},
+	}
+}
+
+func batchOperation(version Version, body *schema.Schema) object {
+	results := object{"type": "object", "additionalProperties": body}
+
+	return object{
+		"summary":    "Metadata about several pages on GOV.UK",
+		"parameters": infoParameters,
+		"requestBody": object{
+			"required": true,
+			"content": object{"application/json": object{"schema": object{
+				"type": "array", "items": object{"type": "string"},
+			}}},
+		},
+		"responses": object{
+			"200": object{
+				"description": "The metadata for each path",
+				"content":     object{version.responseMediaType(): object{"schema": results}},
+			},
+		},
+	}
+}
+
+func queryParameter(name, format, description string) object {
+	parameterSchema := object{"type": "string"}
+	if format != "" {
+		parameterSchema["format"] = format
+	}
+
+	return object{"name": name, "in": "query", "description": description, "schema": parameterSchema}
+}
+
+// OpenAPIHandler serves the OpenAPI document, which is generated once.
+func OpenAPIHandler() func(http.ResponseWriter, *http.Request) {
+	document := NewOpenAPIDocument()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderer.JSON(w, http.StatusOK, document)
+	}
+}
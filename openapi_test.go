@@ -0,0 +1,72 @@
+package main_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	. "github.com/alphagov/metadata-api"
+	"github.com/alphagov/metadata-api/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OpenAPI", func() {
+	document := NewOpenAPIDocument()
+	definitions := document.Components.Schemas
+
+	fixtures, _ := filepath.Glob("fixtures/info_response_*.json")
+
+	// The fixtures are checked strictly, so that the schema can't miss
+	// anything they have.
+	strict := definitions.Strict()
+
+	It("has fixtures to validate", func() {
+		Expect(fixtures).ToNot(BeEmpty())
+	})
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+
+		It("describes "+filepath.Base(fixture)+" as Metadata", func() {
+			body, err := ioutil.ReadFile(fixture)
+			Expect(err).To(BeNil())
+
+			var value interface{}
+			Expect(json.Unmarshal(body, &value)).To(Succeed())
+			Expect(strict.Validate(&schema.Schema{Ref: "#/components/schemas/Metadata"}, value)).To(Succeed())
+		})
+	}
+
+	It("describes the artefact, needs and statistics", func() {
+		for _, name := range []string{"Metadata", "MetadataV2", "Artefact", "Need", "Statistics"} {
+			Expect(definitions).To(HaveKey(name))
+		}
+
+		Expect(definitions["Metadata"].Properties["artefact"]).To(Equal(&schema.Schema{AnyOf: []*schema.Schema{
+			{Ref: "#/components/schemas/ArtefactV1"}, {Type: "null"},
+		}}))
+		Expect(definitions["MetadataV2"].Properties["artefact"]).To(Equal(&schema.Schema{AnyOf: []*schema.Schema{
+			{Ref: "#/components/schemas/Artefact"}, {Type: "null"},
+		}}))
+	})
+
+	It("is served at /openapi.json", func() {
+		server := httptest.NewServer(NewRouter(NewFetcher("", "", pathJSONRequest{}, &Config{})))
+		defer server.Close()
+
+		response, err := http.Get(server.URL + "/openapi.json")
+		Expect(err).To(BeNil())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var served map[string]interface{}
+		body, _ := readResponseBody(response)
+		Expect(json.Unmarshal([]byte(body), &served)).To(Succeed())
+		Expect(served["openapi"]).To(Equal("3.1.0"))
+		Expect(served["paths"]).To(HaveKey("/v2/info/{path}"))
+		Expect(served["components"].(map[string]interface{})["schemas"]).To(HaveKey("Metadata"))
+	})
+})
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/content_store"
+	"github.com/alphagov/metadata-api/performance_platform"
+)
+
+// localePattern matches the locales GOV.UK content is published in, such as
+// "cy" and "zh-tw".
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// InfoOptions are the parameters of an /info request, which are shared by
+// batch requests and exports.
+type InfoOptions struct {
+	// Strict makes any missing section an error, rather than something to
+	// report alongside the sections that could be fetched.
+	Strict bool
+
+	// FollowRedirects responds with the Metadata for where content has moved
+	// to, rather than redirecting the client there.
+	FollowRedirects bool
+
+	// Locale picks the translation to respond for, if it's not the one at
+	// the requested path. AggregateTranslations adds together the
+	// statistics for every translation.
+	Locale                string
+	AggregateTranslations bool
+
+	Statistics  performance_platform.QueryOptions
+	SearchTerms performance_platform.SearchTermsOptions
+	Anomalies   analysis.Options
+	Expand      content_store.ExpandOptions
+}
+
+// ParseInfoOptions reads InfoOptions from query, returning an error that can
+// be shown to the client if any are invalid.
+func ParseInfoOptions(query url.Values) (InfoOptions, error) {
+	statistics, err := performance_platform.ParseQueryOptions(
+		query.Get("from"), query.Get("to"), query.Get("period"))
+	if err != nil {
+		return InfoOptions{}, err
+	}
+
+	searchTerms, err := performance_platform.ParseSearchTermsOptions(
+		query.Get("search_terms_limit"), query.Get("exclude_stop_words"))
+	if err != nil {
+		return InfoOptions{}, err
+	}
+
+	anomalies, err := analysis.ParseOptions(
+		query.Get("anomaly_method"), query.Get("anomaly_threshold"), query.Get("anomaly_window"))
+	if err != nil {
+		return InfoOptions{}, err
+	}
+
+	expand, err := content_store.ParseExpandOptions(query.Get("expand"), query.Get("expand_depth"))
+	if err != nil {
+		return InfoOptions{}, err
+	}
+
+	locale := query.Get("locale")
+	if locale != "" && !localePattern.MatchString(locale) {
+		return InfoOptions{}, fmt.Errorf("locale must be a locale such as cy or zh-tw, not %q", locale)
+	}
+
+	return InfoOptions{
+		Strict:          query.Get("strict") == "true",
+		FollowRedirects: query.Get("follow_redirects") == "true",
+		Locale:          locale,
+		Statistics:      statistics,
+		SearchTerms:     searchTerms,
+		Anomalies:       anomalies,
+		Expand:          expand,
+
+		AggregateTranslations: query.Get("aggregate_translations") == "true",
+	}, nil
+}
+
+// key identifies the Metadata fetched with these options, which Strict has
+// no effect on. It doesn't change from one day to the next for a window
+// that moves on each day.
+func (options InfoOptions) key() string {
+	return options.Statistics.Key() + "|" + options.SearchTerms.String() + "|" + options.Anomalies.String() +
+		"|" + options.Expand.String() + "|" + strconv.FormatBool(options.FollowRedirects) +
+		"|" + options.Locale + "|" + strconv.FormatBool(options.AggregateTranslations)
+}
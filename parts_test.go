@@ -0,0 +1,169 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/alphagov/metadata-api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func guideItem(path, locale string) string {
+	return fmt.Sprintf(`{"base_path": %q, "content_id": "id", "title": "Vehicle tax", "document_type": "guide",
+		"locale": %q, "details": {"parts": [{"slug": "overview", "title": "Overview"}, {"slug": "rates", "title": "Rates"}]},
+		"links": {"available_translations": [
+			{"content_id": "id", "locale": "en", "title": "Vehicle tax", "base_path": "/vehicle-tax"},
+			{"content_id": "id", "locale": "cy", "title": "Treth cerbyd", "base_path": "/vehicle-tax.cy"}]}}`,
+		path, locale)
+}
+
+var _ = Describe("Parts", func() {
+	var (
+		server, performanceAPI *httptest.Server
+		contentStore           pathJSONRequest
+	)
+
+	BeforeEach(func() {
+		pageViews := map[string]int{
+			"/vehicle-tax":             10,
+			"/vehicle-tax/overview":    20,
+			"/vehicle-tax/rates":       40,
+			"/vehicle-tax.cy/rates":    3,
+			"/vehicle-tax.cy/overview": 1,
+		}
+
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "page-statistics") {
+				fmt.Fprintln(w, `{"data":[]}`)
+				return
+			}
+
+			query := r.URL.Query()
+			exact := strings.TrimPrefix(query.Get("filter_by"), "pagePath:")
+			prefix := strings.TrimPrefix(query.Get("filter_by_prefix"), "pagePath:")
+
+			data := []string{}
+			for path, views := range pageViews {
+				if path == exact || (prefix != "" && strings.HasPrefix(path, prefix)) {
+					data = append(data, fmt.Sprintf(`{"pagePath": %q, "values": [
+						{"_start_at": "2014-07-03T00:00:00+00:00", "uniquePageviews:sum": %d}]}`, path, views))
+				}
+			}
+			fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(data, ","))
+		})
+
+		contentStore = pathJSONRequest{
+			"/vehicle-tax":    guideItem("/vehicle-tax", "en"),
+			"/vehicle-tax.cy": guideItem("/vehicle-tax.cy", "cy"),
+		}
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, contentStore, &Config{})
+		server = httptest.NewServer(NewRouter(fetcher))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		performanceAPI.Close()
+	})
+
+	It("responds with the whole artefact for a part, with the part current", func() {
+		response, metadata := getJSON(server.URL + "/v2/info/vehicle-tax/rates")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		artefact := metadata["artefact"].(map[string]interface{})
+		Expect(artefact["web_url"]).To(HaveSuffix("/vehicle-tax"))
+		parts := artefact["details"].(map[string]interface{})["parts"].([]interface{})
+		Expect(parts[0]).NotTo(HaveKey("current"))
+		Expect(parts[1]).To(HaveKeyWithValue("current", true))
+
+		_, metadata = getJSON(server.URL + "/v2/info/vehicle-tax")
+		parts = metadata["artefact"].(map[string]interface{})["details"].(map[string]interface{})["parts"].([]interface{})
+		Expect(parts[1]).NotTo(HaveKey("current"))
+	})
+
+	It("scopes the statistics to the part", func() {
+		_, metadata := getJSON(server.URL + "/v1/info/vehicle-tax/rates")
+		Expect(pageViewsTotal(metadata)).To(Equal(40.0))
+		Expect(metadata["performance"]).NotTo(HaveKey("parts"))
+
+		_, metadata = getJSON(server.URL + "/v1/info/vehicle-tax")
+		Expect(pageViewsTotal(metadata)).To(Equal(70.0))
+
+		_, metadata = getJSON(server.URL + "/v1/info/vehicle-tax/rates?aggregate_translations=true")
+		Expect(pageViewsTotal(metadata)).To(Equal(43.0))
+	})
+
+	It("breaks down the statistics for every translation by part", func() {
+		_, metadata := getJSON(server.URL + "/v1/info/vehicle-tax?aggregate_translations=true")
+		Expect(pageViewsTotal(metadata)).To(Equal(74.0))
+
+		performance := metadata["performance"].(map[string]interface{})
+		parts := performance["parts"].([]interface{})
+		Expect(pageViewsTotal(map[string]interface{}{"performance": parts[0]})).To(Equal(31.0))
+		Expect(pageViewsTotal(map[string]interface{}{"performance": parts[1]})).To(Equal(43.0))
+		Expect(pageViewsTotal(map[string]interface{}{"performance": performance["unmatched"]})).To(Equal(0.0))
+	})
+
+	It("responds with the same part of a translation", func() {
+		_, metadata := getJSON(server.URL + "/v2/info/vehicle-tax/rates?locale=cy")
+		artefact := metadata["artefact"].(map[string]interface{})
+		Expect(artefact["locale"]).To(Equal("cy"))
+		parts := artefact["details"].(map[string]interface{})["parts"].([]interface{})
+		Expect(parts[1]).To(HaveKeyWithValue("current", true))
+		Expect(pageViewsTotal(metadata)).To(Equal(3.0))
+	})
+
+	It("responds with the part that a path is beneath", func() {
+		response, metadata := getJSON(server.URL + "/v2/info/vehicle-tax/rates/print")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		artefact := metadata["artefact"].(map[string]interface{})
+		Expect(artefact["web_url"]).To(HaveSuffix("/vehicle-tax"))
+		parts := artefact["details"].(map[string]interface{})["parts"].([]interface{})
+		Expect(parts[1]).To(HaveKeyWithValue("current", true))
+		Expect(pageViewsTotal(metadata)).To(Equal(40.0))
+	})
+
+	It("only asks the content store for each path once", func() {
+		var requests int32
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL,
+			countingJSONRequest{contentStore, &requests}, &Config{ArtefactCacheTTL: time.Minute})
+		server.Close()
+		server = httptest.NewServer(NewRouter(fetcher))
+
+		response, _ := getJSON(server.URL + "/v1/info/vehicle-tax/rates/print")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+
+		response, _ = getJSON(server.URL + "/v1/info/vehicle-tax/rates/print")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		response, _ = getJSON(server.URL + "/v1/info/nothing/at/all")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(6)))
+
+		response, _ = getJSON(server.URL + "/v1/info/nothing/at/all")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(6)))
+	})
+
+	It("responds 404 for paths that aren't a part", func() {
+		for _, path := range []string{"/v1/info/vehicle-tax/prices", "/v1/info/vehicle-tax/prices/print", "/v1/info/nothing/rates"} {
+			response, _ := getJSON(server.URL + path)
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound), path)
+		}
+	})
+
+	It("follows redirects to a part", func() {
+		contentStore["/vehicle-tax/old"] = redirectItem("/vehicle-tax/old", "/vehicle-tax/rates")
+
+		response, metadata := getJSON(server.URL + "/v1/info/vehicle-tax/old")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Request.URL.Path).To(Equal("/v1/info/vehicle-tax/rates"))
+		Expect(pageViewsTotal(metadata)).To(Equal(40.0))
+	})
+})
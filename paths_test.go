@@ -0,0 +1,105 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/alphagov/metadata-api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Paths", func() {
+	var server, performanceAPI *httptest.Server
+
+	BeforeEach(func() {
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":[]}`)
+		})
+
+		// Content is found by the path in the content-store URL, which is
+		// percent-encoded.
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, pathJSONRequest{
+			"/government/one": contentItem("/government/one"),
+			"/caf%C3%A9":      contentItem("/café"),
+			"/what%3F":        contentItem("/what?"),
+			"/old-one":        redirectItem("/old-one", "/government/one"),
+		}, &Config{BatchMaxPaths: 3, BatchConcurrency: 3})
+		server = httptest.NewServer(NewRouter(fetcher))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		performanceAPI.Close()
+	})
+
+	for _, path := range []string{
+		"/v1/info/government/one",
+		"/v1/info/government/one/",
+		"/v1/info//government///one",
+		"/v1/info/government/one?period=day#top",
+		"/v1/info?url=" + url.QueryEscape("https://www.gov.uk/government/one/?utm_source=x#top"),
+		"/v1/info/?url=/government/one",
+		"/info?url=government/one",
+	} {
+		path := path
+		It("looks up "+path+" as /government/one", func() {
+			response, metadata := getJSON(server.URL + path)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(metadata["artefact"].(map[string]interface{})["web_url"]).To(HaveSuffix("/government/one"))
+		})
+	}
+
+	It("decodes paths once, and encodes them for content-store", func() {
+		response, metadata := getJSON(server.URL + "/v1/info/caf%C3%A9")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(metadata["artefact"].(map[string]interface{})["web_url"]).To(HaveSuffix("/café"))
+
+		response, _ = getJSON(server.URL + "/v1/info/what%3F")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("rejects URLs that aren't on GOV.UK and paths that escape", func() {
+		response, metadata := getJSON(server.URL + "/v1/info?url=" + url.QueryEscape("https://www.example.com/government/one"))
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(metadata["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "path https://www.example.com/government/one is not on GOV.UK",
+		}))
+
+		response, _ = getJSON(server.URL + "/v1/info?url=" + url.QueryEscape("/government/../admin"))
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+
+		response, _ = getJSON(server.URL + "/v1/info?url=" + url.QueryEscape("/government/%2e%2e/admin"))
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("redirects to the path, without the url parameter", func() {
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+
+		response, err := client.Get(server.URL + "/v1/info?period=day&url=" + url.QueryEscape("https://www.gov.uk/old-one"))
+		Expect(err).To(BeNil())
+		Expect(response.StatusCode).To(Equal(http.StatusMovedPermanently))
+		Expect(response.Header.Get("Location")).To(Equal("/v1/info/government/one?period=day"))
+	})
+
+	It("normalises the paths in batches", func() {
+		response, err := http.Post(server.URL+"/v1/info/batch", "application/json",
+			strings.NewReader(`["https://www.gov.uk/government/one", "/../etc/passwd"]`))
+		Expect(err).To(BeNil())
+
+		var results map[string]map[string]interface{}
+		body, _ := readResponseBody(response)
+		Expect(json.Unmarshal([]byte(body), &results)).To(Succeed())
+		Expect(results["https://www.gov.uk/government/one"]["_response_info"]).To(Equal(map[string]interface{}{"status": "ok"}))
+		Expect(results["/../etc/passwd"]["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "path /../etc/passwd has a relative segment", "status_code": 400.0,
+		}))
+	})
+})
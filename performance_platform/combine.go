@@ -0,0 +1,29 @@
+package performance_platform
+
+// Combine adds together the statistics for several pages, such as the
+// translations of one page, fetched by SlugStatistics for the same window.
+// Each value keeps the path it's for, and the searches for each term are
+// added together. Combine returns a new Statistics, leaving those given as
+// they were.
+func Combine(all ...*Statistics) *Statistics {
+	combined := &Statistics{
+		PageViews:      []Statistic{},
+		Searches:       []Statistic{},
+		ProblemReports: []Statistic{},
+	}
+
+	var terms SearchTerms
+	for _, statistics := range all {
+		combined.PageViews = append(combined.PageViews, statistics.PageViews...)
+		combined.Searches = append(combined.Searches, statistics.Searches...)
+		combined.ProblemReports = append(combined.ProblemReports, statistics.ProblemReports...)
+		terms = append(terms, statistics.SearchTerms...)
+
+		combined.StartAt, combined.EndAt = statistics.StartAt, statistics.EndAt
+		combined.Period = statistics.Period
+	}
+
+	combined.SearchTerms = terms.merged(false)
+	combined.Summary = summariseStatistics(combined.Period, combined.PageViews, combined.Searches, combined.ProblemReports)
+	return combined
+}
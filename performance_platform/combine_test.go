@@ -0,0 +1,53 @@
+package performance_platform_test
+
+import (
+	"time"
+
+	. "github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Combine", func() {
+	day := func(n int) time.Time {
+		return time.Date(2017, 3, 1+n, 0, 0, 0, 0, time.UTC)
+	}
+
+	It("adds together the statistics for each page", func() {
+		english := &Statistics{
+			PageViews: []Statistic{{Path: "/a", Timestamp: day(0), Value: 10}, {Path: "/a", Timestamp: day(1), Value: 20}},
+			SearchTerms: SearchTerms{
+				{Keyword: "tax", TotalSearches: 3, Searches: []Statistic{{Timestamp: day(0), Value: 3}}},
+			},
+			StartAt: day(0),
+			EndAt:   day(2),
+		}
+		welsh := &Statistics{
+			PageViews:      []Statistic{{Path: "/a.cy", Timestamp: day(1), Value: 5}},
+			ProblemReports: []Statistic{{Path: "/a.cy", Timestamp: day(1), Value: 1}},
+			SearchTerms: SearchTerms{
+				{Keyword: "treth", TotalSearches: 4, Searches: []Statistic{{Timestamp: day(1), Value: 4}}},
+				{Keyword: "tax", TotalSearches: 2, Searches: []Statistic{{Timestamp: day(1), Value: 2}}},
+			},
+			StartAt: day(0),
+			EndAt:   day(2),
+		}
+
+		combined := Combine(english, welsh)
+		Expect(combined.PageViews).To(HaveLen(3))
+		Expect(combined.Searches).To(Equal([]Statistic{}))
+		Expect(combined.ProblemReports).To(Equal(welsh.ProblemReports))
+		Expect(combined.SearchTerms).To(Equal(SearchTerms{
+			{Keyword: "tax", TotalSearches: 5, Searches: []Statistic{{Timestamp: day(0), Value: 3}, {Timestamp: day(1), Value: 2}}},
+			{Keyword: "treth", TotalSearches: 4, Searches: []Statistic{{Timestamp: day(1), Value: 4}}},
+		}))
+		Expect(combined.Summary.PageViews.Total).To(Equal(35))
+		Expect(combined.Summary.PageViews.Max).To(Equal(25))
+		Expect(combined.StartAt).To(Equal(day(0)))
+		Expect(combined.EndAt).To(Equal(day(2)))
+
+		Expect(english.SearchTerms[0].Searches).To(HaveLen(1))
+		Expect(english.PageViews).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,104 @@
+package performance_platform
+
+import "strings"
+
+// Part is a page of a multipart item, at Slug beneath the item's path.
+type Part struct {
+	Slug   string
+	Title  string
+	WebURL string
+}
+
+// PartStatistics are the statistics for the paths belonging to one part of
+// a multipart item, or for those belonging to none of them.
+type PartStatistics struct {
+	Title          string             `json:"title,omitempty"`
+	WebURL         string             `json:"web_url,omitempty"`
+	PageViews      []Statistic        `json:"page_views"`
+	Searches       []Statistic        `json:"searches"`
+	ProblemReports []Statistic        `json:"problem_reports"`
+	Summary        *StatisticsSummary `json:"summary"`
+}
+
+// Beneath returns a copy of statistics with only the values for basePath
+// and the paths beneath it. Backdrop's prefix filter matches every path that
+// starts with the same characters, including those of other pages such as
+// basePath's translation at basePath.cy.
+func (statistics Statistics) Beneath(basePath string) *Statistics {
+	beneath := func(series []Statistic) []Statistic {
+		kept := []Statistic{}
+		for _, statistic := range series {
+			if statistic.Path == basePath || strings.HasPrefix(statistic.Path, basePath+"/") {
+				kept = append(kept, statistic)
+			}
+		}
+		return kept
+	}
+
+	statistics.PageViews = beneath(statistics.PageViews)
+	statistics.Searches = beneath(statistics.Searches)
+	statistics.ProblemReports = beneath(statistics.ProblemReports)
+	statistics.Summary = summariseStatistics(statistics.Period, statistics.PageViews, statistics.Searches,
+		statistics.ProblemReports)
+	return &statistics
+}
+
+// WithParts returns a copy of statistics fetched for every path beneath
+// basePaths, broken down into parts. Each part has the statistics for its
+// path beneath any of basePaths and for any beneath that, and the first part
+// also has those for basePaths themselves, which is where it's shown. There
+// is more than one base path when the statistics are for every translation
+// of an item, whose parts have the same slugs. Statistics for any other
+// path, such as a smart answer's questions, are in Unmatched.
+func (statistics Statistics) WithParts(basePaths []string, parts []Part) *Statistics {
+	breakdown := make([]*PartStatistics, len(parts))
+	for i, part := range parts {
+		breakdown[i] = &PartStatistics{Title: part.Title, WebURL: part.WebURL}
+	}
+	unmatched := &PartStatistics{}
+
+	partFor := func(path string) *PartStatistics {
+		for _, basePath := range basePaths {
+			if path == basePath && len(parts) > 0 {
+				return breakdown[0]
+			}
+			for i, part := range parts {
+				partPath := basePath + "/" + part.Slug
+				if path == partPath || strings.HasPrefix(path, partPath+"/") {
+					return breakdown[i]
+				}
+			}
+		}
+		return unmatched
+	}
+
+	for _, statistic := range statistics.PageViews {
+		part := partFor(statistic.Path)
+		part.PageViews = append(part.PageViews, statistic)
+	}
+	for _, statistic := range statistics.Searches {
+		part := partFor(statistic.Path)
+		part.Searches = append(part.Searches, statistic)
+	}
+	for _, statistic := range statistics.ProblemReports {
+		part := partFor(statistic.Path)
+		part.ProblemReports = append(part.ProblemReports, statistic)
+	}
+
+	for _, part := range append(breakdown, unmatched) {
+		if part.PageViews == nil {
+			part.PageViews = []Statistic{}
+		}
+		if part.Searches == nil {
+			part.Searches = []Statistic{}
+		}
+		if part.ProblemReports == nil {
+			part.ProblemReports = []Statistic{}
+		}
+		part.Summary = summariseStatistics(statistics.Period, part.PageViews, part.Searches, part.ProblemReports)
+	}
+
+	statistics.Parts = breakdown
+	statistics.Unmatched = unmatched
+	return &statistics
+}
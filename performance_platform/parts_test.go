@@ -0,0 +1,81 @@
+package performance_platform_test
+
+import (
+	. "github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithParts", func() {
+	var statistics Statistics
+
+	BeforeEach(func() {
+		statistics = Statistics{
+			PageViews: append(append(append(daily("/guide", 10), daily("/guide/eligibility", 5)...),
+				daily("/guide/how-to-apply/print", 2)...), daily("/guide/y/question-1", 1)...),
+			Searches:       daily("/guide/eligibility", 3),
+			ProblemReports: []Statistic{},
+		}
+	})
+
+	It("groups the statistics for each path by part", func() {
+		withParts := statistics.WithParts([]string{"/guide"}, []Part{
+			{Slug: "overview", Title: "Overview", WebURL: "https://www.gov.uk/guide/overview"},
+			{Slug: "eligibility", Title: "Eligibility", WebURL: "https://www.gov.uk/guide/eligibility"},
+			{Slug: "how-to-apply", Title: "How to apply", WebURL: "https://www.gov.uk/guide/how-to-apply"},
+		})
+
+		Expect(withParts.Parts).To(HaveLen(3))
+
+		overview := withParts.Parts[0]
+		Expect(overview.Title).To(Equal("Overview"))
+		Expect(overview.WebURL).To(Equal("https://www.gov.uk/guide/overview"))
+		Expect(overview.PageViews).To(Equal(daily("/guide", 10)))
+		Expect(overview.Searches).To(BeEmpty())
+
+		eligibility := withParts.Parts[1]
+		Expect(eligibility.PageViews).To(Equal(daily("/guide/eligibility", 5)))
+		Expect(eligibility.Searches).To(Equal(daily("/guide/eligibility", 3)))
+		Expect(eligibility.Summary.PageViews.Total).To(Equal(5))
+
+		Expect(withParts.Parts[2].PageViews).To(Equal(daily("/guide/how-to-apply/print", 2)))
+		Expect(withParts.Unmatched.PageViews).To(Equal(daily("/guide/y/question-1", 1)))
+	})
+
+	It("groups the statistics for each translation into the same parts", func() {
+		statistics.PageViews = append(statistics.PageViews, append(daily("/guide.cy", 4), daily("/guide.cy/eligibility", 6)...)...)
+
+		withParts := statistics.WithParts([]string{"/guide", "/guide.cy"}, []Part{{Slug: "overview"}, {Slug: "eligibility"}})
+		Expect(withParts.Parts[0].PageViews).To(Equal(append(daily("/guide", 10), daily("/guide.cy", 4)...)))
+		Expect(withParts.Parts[1].PageViews).To(Equal(append(daily("/guide/eligibility", 5), daily("/guide.cy/eligibility", 6)...)))
+		Expect(withParts.Unmatched.PageViews).To(Equal(append(daily("/guide/how-to-apply/print", 2), daily("/guide/y/question-1", 1)...)))
+	})
+
+	It("keeps only the statistics for paths beneath a base path", func() {
+		statistics.PageViews = append(statistics.PageViews, append(daily("/guide.cy", 4), daily("/guidelines", 6)...)...)
+
+		beneath := statistics.Beneath("/guide")
+		Expect(beneath.PageViews).To(HaveLen(4))
+		for _, statistic := range beneath.PageViews {
+			Expect(statistic.Path).To(HavePrefix("/guide"))
+			Expect(statistic.Path).ToNot(HavePrefix("/guide.cy"))
+			Expect(statistic.Path).ToNot(HavePrefix("/guidelines"))
+		}
+		Expect(beneath.Searches).To(Equal(statistics.Searches))
+		Expect(beneath.Summary.PageViews.Total).To(Equal(18))
+		Expect(statistics.PageViews).To(HaveLen(6))
+	})
+
+	It("leaves the statistics it was called on alone", func() {
+		statistics.WithParts([]string{"/guide"}, []Part{{Slug: "overview"}})
+		Expect(statistics.Parts).To(BeNil())
+		Expect(statistics.Unmatched).To(BeNil())
+	})
+
+	It("puts everything in Unmatched when there are no parts", func() {
+		withParts := statistics.WithParts([]string{"/guide"}, nil)
+		Expect(withParts.Parts).To(BeEmpty())
+		Expect(withParts.Unmatched.PageViews).To(HaveLen(4))
+	})
+})
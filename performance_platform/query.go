@@ -0,0 +1,166 @@
+package performance_platform
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+const dateFormat = "2006-01-02"
+
+// defaultDays is how many days of statistics are fetched if the start of the
+// window isn't given.
+const defaultDays = 42
+
+// maxPeriods caps the length of a window in each of the periods Backdrop can
+// group by.
+var maxPeriods = map[string]int{
+	"day":   366,
+	"week":  156,
+	"month": 60,
+}
+
+// QueryOptions selects the statistics to fetch: one value per Period from
+// StartAt up to, but not including, EndAt. Both are at the start of a
+// Period, in UTC. The zero QueryOptions is DefaultQueryOptions.
+type QueryOptions struct {
+	Period  string
+	StartAt time.Time
+	EndAt   time.Time
+
+	// relativeStart and relativeEnd are set when the window starts a fixed
+	// number of days before its end, and ends today, rather than on dates
+	// that were asked for, so that it moves on each day.
+	relativeStart bool
+	relativeEnd   bool
+}
+
+// DefaultQueryOptions selects daily statistics for the 42 days before today.
+func DefaultQueryOptions() QueryOptions {
+	options, _ := ParseQueryOptions("", "", "")
+	return options
+}
+
+// ParseQueryOptions validates the from, to and period parameters of a
+// request, any of which may be empty. from and to are dates such as
+// 2017-03-23 and are both included in the window, which is widened to whole
+// periods. Statistics aren't available for today yet, so the window never
+// ends later than that.
+func ParseQueryOptions(from, to, period string) (QueryOptions, error) {
+	if period == "" {
+		period = "day"
+	}
+	if _, ok := maxPeriods[period]; !ok {
+		return QueryOptions{}, fmt.Errorf("period must be one of day, week or month, not %q", period)
+	}
+
+	today := now.New(time.Now().UTC()).BeginningOfDay()
+
+	endAt := today
+	if to != "" {
+		date, err := time.Parse(dateFormat, to)
+		if err != nil {
+			return QueryOptions{}, fmt.Errorf("to must be a date such as 2017-03-23, not %q", to)
+		}
+		if date.Before(today) {
+			endAt = date.AddDate(0, 0, 1)
+		}
+	}
+
+	startAt := endAt.AddDate(0, 0, -defaultDays)
+	if from != "" {
+		date, err := time.Parse(dateFormat, from)
+		if err != nil {
+			return QueryOptions{}, fmt.Errorf("from must be a date such as 2017-03-23, not %q", from)
+		}
+		if !date.Before(endAt) {
+			return QueryOptions{}, fmt.Errorf("from must be before to and today")
+		}
+		startAt = date
+	}
+
+	options := QueryOptions{
+		Period:        period,
+		StartAt:       PeriodStart(period, startAt),
+		EndAt:         PeriodStart(period, endAt.AddDate(0, 0, -1)),
+		relativeStart: from == "",
+		relativeEnd:   endAt.Equal(today),
+	}
+	options.EndAt = options.next(options.EndAt)
+
+	if options.periods() > maxPeriods[period] {
+		return QueryOptions{}, fmt.Errorf("at most %d %ss of statistics can be fetched at once",
+			maxPeriods[period], period)
+	}
+
+	return options, nil
+}
+
+// String identifies the window, for use in cache keys.
+func (options QueryOptions) String() string {
+	return fmt.Sprintf("%s:%s:%s", options.Period,
+		options.StartAt.Format(dateFormat), options.EndAt.Format(dateFormat))
+}
+
+// Key identifies the window as it was asked for, so that a window that
+// moves on each day, such as the default one, has the same Key from one day
+// to the next. The zero QueryOptions has the same Key as
+// DefaultQueryOptions.
+func (options QueryOptions) Key() string {
+	if options.Period == "" {
+		options = DefaultQueryOptions()
+	}
+
+	start, end := options.StartAt.Format(dateFormat), options.EndAt.Format(dateFormat)
+	if options.relativeStart {
+		start = fmt.Sprintf("-%dd", defaultDays)
+	}
+	if options.relativeEnd {
+		end = "today"
+	}
+	return fmt.Sprintf("%s:%s:%s", options.Period, start, end)
+}
+
+func (options QueryOptions) next(t time.Time) time.Time {
+	switch options.Period {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// complete is when the statistics in the window are complete up to: its
+// end, unless that's still to come.
+func (options QueryOptions) complete() time.Time {
+	today := now.New(time.Now().UTC()).BeginningOfDay()
+	if options.EndAt.After(today) {
+		return today
+	}
+	return options.EndAt
+}
+
+// periods counts the periods in the window, giving up once there are more
+// than can be fetched.
+func (options QueryOptions) periods() int {
+	periods := 0
+	for t := options.StartAt; t.Before(options.EndAt) && periods <= maxPeriods[options.Period]; t = options.next(t) {
+		periods++
+	}
+	return periods
+}
+
+// PeriodStart is the start of the period containing date: the day itself,
+// the Monday of its week or the first of its month. An empty period is
+// "day".
+func PeriodStart(period string, date time.Time) time.Time {
+	switch period {
+	case "week":
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
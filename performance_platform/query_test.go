@@ -0,0 +1,132 @@
+package performance_platform_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	. "github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/alphagov/performanceplatform-client-go"
+	"github.com/jinzhu/now"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func date(value string) time.Time {
+	t, _ := time.Parse("2006-01-02", value)
+	return t
+}
+
+var _ = Describe("QueryOptions", func() {
+	today := now.New(time.Now().UTC()).BeginningOfDay()
+
+	It("defaults to the 42 days before today", func() {
+		options, err := ParseQueryOptions("", "", "")
+		Expect(err).To(BeNil())
+		Expect(options.Period).To(Equal("day"))
+		Expect(options.StartAt).To(Equal(today.AddDate(0, 0, -42)))
+		Expect(options.EndAt).To(Equal(today))
+		Expect(DefaultQueryOptions()).To(Equal(options))
+	})
+
+	It("has a Key that stays the same from day to day for windows relative to today", func() {
+		recent := today.AddDate(0, 0, -30).Format("2006-01-02")
+
+		for _, c := range []struct{ from, to, period, key string }{
+			{"", "", "", "day:-42d:today"},
+			{"", "", "week", "week:-42d:today"},
+			{recent, "", "day", "day:" + recent + ":today"},
+			{"", today.AddDate(0, 0, 10).Format("2006-01-02"), "day", "day:-42d:today"},
+			{"2017-01-01", "2017-01-31", "day", "day:2017-01-01:2017-02-01"},
+			{"", "2017-01-31", "day", "day:-42d:2017-02-01"},
+		} {
+			options, err := ParseQueryOptions(c.from, c.to, c.period)
+			Expect(err).To(BeNil())
+			Expect(options.Key()).To(Equal(c.key))
+		}
+
+		Expect(QueryOptions{}.Key()).To(Equal(DefaultQueryOptions().Key()))
+	})
+
+	It("includes both from and to", func() {
+		options, err := ParseQueryOptions("2017-01-01", "2017-01-31", "day")
+		Expect(err).To(BeNil())
+		Expect(options.StartAt).To(Equal(date("2017-01-01")))
+		Expect(options.EndAt).To(Equal(date("2017-02-01")))
+	})
+
+	It("widens the window to whole weeks, starting on Mondays", func() {
+		options, err := ParseQueryOptions("2017-03-22", "2017-03-28", "week")
+		Expect(err).To(BeNil())
+		Expect(options.StartAt).To(Equal(date("2017-03-20")))
+		Expect(options.EndAt).To(Equal(date("2017-04-03")))
+	})
+
+	It("widens the window to whole months", func() {
+		options, err := ParseQueryOptions("2017-01-15", "2017-03-31", "month")
+		Expect(err).To(BeNil())
+		Expect(options.StartAt).To(Equal(date("2017-01-01")))
+		Expect(options.EndAt).To(Equal(date("2017-04-01")))
+	})
+
+	It("never ends after today", func() {
+		options, err := ParseQueryOptions("", today.AddDate(0, 0, 10).Format("2006-01-02"), "")
+		Expect(err).To(BeNil())
+		Expect(options.EndAt).To(Equal(today))
+	})
+
+	for _, invalid := range []struct{ description, from, to, period string }{
+		{"an unknown period", "", "", "year"},
+		{"a malformed from", "last week", "", ""},
+		{"a malformed to", "", "2017-13-01", ""},
+		{"from after to", "2017-02-01", "2017-01-01", ""},
+		{"from today", today.Format("2006-01-02"), "", ""},
+		{"too many days", "2015-01-01", "2017-01-01", "day"},
+		{"too many months", "2000-01-01", "2017-01-01", "month"},
+	} {
+		invalid := invalid
+
+		It("rejects "+invalid.description, func() {
+			_, err := ParseQueryOptions(invalid.from, invalid.to, invalid.period)
+			Expect(err).ToNot(BeNil())
+		})
+	}
+
+	It("is passed on to every query", func() {
+		server := ghttp.NewServer()
+		defer server.Close()
+
+		var (
+			mutex   sync.Mutex
+			queries []url.Values
+		)
+		for _, dataset := range []string{"page-statistics", "search-terms", "page-contacts"} {
+			server.RouteToHandler("GET", "/data/govuk-info/"+dataset, func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				queries = append(queries, r.URL.Query())
+				mutex.Unlock()
+				ghttp.RespondWith(http.StatusOK, `{"data": []}`)(w, r)
+			})
+		}
+
+		options, _ := ParseQueryOptions("2017-01-01", "2017-03-31", "month")
+		client := performanceclient.NewDataClient(server.URL(), logrus.New())
+		statistics, err := SlugStatistics(context.Background(), client, "/foo", false, options)
+		Expect(err).To(BeNil())
+		Expect(statistics.EndAt).To(Equal(date("2017-04-01")))
+
+		Expect(queries).To(HaveLen(4))
+		for _, query := range queries {
+			Expect(query.Get("period")).To(Equal("month"))
+			Expect(query.Get("start_at")).To(Equal("2017-01-01T00:00:00Z"))
+			Expect(query.Get("end_at")).To(Equal("2017-04-01T00:00:00Z"))
+			Expect(query.Get("duration")).To(BeEmpty())
+		}
+	})
+})
@@ -0,0 +1,169 @@
+package performance_platform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchTermsLimit = 10
+	maxSearchTermsLimit     = 100
+)
+
+// stopWords are left out of keywords when ExcludeStopWords is set.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "for": true, "from": true,
+	"how": true, "i": true, "in": true, "is": true, "it": true, "my": true,
+	"of": true, "on": true, "or": true, "the": true, "to": true, "what": true,
+	"when": true, "where": true, "with": true,
+}
+
+// SearchTermsOptions choose which search terms are shown: the Limit most
+// searched for, optionally with stop words such as "how" and "the" left out
+// of their keywords. The zero SearchTermsOptions shows the top 10.
+type SearchTermsOptions struct {
+	Limit            int
+	ExcludeStopWords bool
+}
+
+// ParseSearchTermsOptions validates the search_terms_limit and
+// exclude_stop_words parameters of a request, either of which may be empty.
+func ParseSearchTermsOptions(limit, excludeStopWords string) (SearchTermsOptions, error) {
+	options := SearchTermsOptions{Limit: defaultSearchTermsLimit}
+
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxSearchTermsLimit {
+			return SearchTermsOptions{}, fmt.Errorf("search_terms_limit must be a number from 1 to %d, not %q",
+				maxSearchTermsLimit, limit)
+		}
+		options.Limit = value
+	}
+
+	switch excludeStopWords {
+	case "", "false":
+	case "true":
+		options.ExcludeStopWords = true
+	default:
+		return SearchTermsOptions{}, fmt.Errorf("exclude_stop_words must be true or false, not %q", excludeStopWords)
+	}
+
+	return options, nil
+}
+
+// String identifies the options, for use in cache keys.
+func (options SearchTermsOptions) String() string {
+	return fmt.Sprintf("%d:%t", options.Limit, options.ExcludeStopWords)
+}
+
+// NormaliseKeyword lower-cases keyword and collapses the space in it, so that
+// searches for "Passport" and "passport " count as the same term.
+func NormaliseKeyword(keyword string, excludeStopWords bool) string {
+	words := strings.Fields(strings.ToLower(keyword))
+	if !excludeStopWords {
+		return strings.Join(words, " ")
+	}
+
+	kept := words[:0]
+	for _, word := range words {
+		if !stopWords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// merged normalises every keyword and adds together the searches for terms
+// that are then the same, dropping any left without a keyword. The result
+// is sorted with the most searched for first.
+func (terms SearchTerms) merged(excludeStopWords bool) SearchTerms {
+	byKeyword := make(map[string]*SearchTerm)
+	merged := SearchTerms{}
+
+	for _, term := range terms {
+		keyword := NormaliseKeyword(term.Keyword, excludeStopWords)
+		if keyword == "" {
+			continue
+		}
+
+		existing, ok := byKeyword[keyword]
+		if !ok {
+			byKeyword[keyword] = &SearchTerm{Keyword: keyword, TotalSearches: term.TotalSearches, Searches: term.Searches}
+			continue
+		}
+
+		existing.TotalSearches += term.TotalSearches
+		existing.Searches = mergeSeries(existing.Searches, term.Searches)
+	}
+
+	for _, term := range byKeyword {
+		merged = append(merged, *term)
+	}
+	sort.Sort(merged)
+	return merged
+}
+
+// mergeSeries adds together the values in a and b for each date.
+func mergeSeries(a, b []Statistic) []Statistic {
+	merged := make([]Statistic, 0, len(a)+len(b))
+	index := make(map[time.Time]int)
+
+	for _, statistic := range append(append([]Statistic{}, a...), b...) {
+		if i, ok := index[statistic.Timestamp]; ok {
+			merged[i].Value += statistic.Value
+			continue
+		}
+		index[statistic.Timestamp] = len(merged)
+		merged = append(merged, statistic)
+	}
+
+	sort.Sort(statisticsByTimestamp(merged))
+	return merged
+}
+
+type statisticsByTimestamp []Statistic
+
+func (statistics statisticsByTimestamp) Len() int { return len(statistics) }
+func (statistics statisticsByTimestamp) Swap(i, j int) {
+	statistics[i], statistics[j] = statistics[j], statistics[i]
+}
+func (statistics statisticsByTimestamp) Less(i, j int) bool {
+	return statistics[i].Timestamp.Before(statistics[j].Timestamp)
+}
+
+// WithSearchTerms returns a copy of statistics showing only the search terms
+// chosen by options, each with its share of all the searches made from the
+// page. Every term, merged as options ask, is kept in AllSearchTerms.
+func (statistics Statistics) WithSearchTerms(options SearchTermsOptions) *Statistics {
+	if options.Limit == 0 {
+		options.Limit = defaultSearchTermsLimit
+	}
+
+	total := 0
+	for _, term := range statistics.SearchTerms {
+		total += term.TotalSearches
+	}
+
+	terms := statistics.SearchTerms
+	if options.ExcludeStopWords {
+		terms = terms.merged(true)
+	}
+	statistics.AllSearchTerms = terms
+	if len(terms) > options.Limit {
+		terms = terms[:options.Limit]
+	}
+
+	statistics.SearchTerms = make(SearchTerms, len(terms))
+	for i, term := range terms {
+		if total > 0 {
+			term.PercentageOfTotal = round(100*float64(term.TotalSearches)/float64(total), 2)
+		}
+		statistics.SearchTerms[i] = term
+	}
+
+	return &statistics
+}
@@ -0,0 +1,135 @@
+package performance_platform_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/alphagov/performanceplatform-client-go"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func searchTerm(keyword string, values ...int) SearchTerm {
+	series := daily("", values...)
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return SearchTerm{Keyword: keyword, TotalSearches: total, Searches: series}
+}
+
+var _ = Describe("SearchTerms", func() {
+	Describe("ParseSearchTermsOptions", func() {
+		It("defaults to the top 10 with stop words", func() {
+			options, err := ParseSearchTermsOptions("", "")
+			Expect(err).To(BeNil())
+			Expect(options).To(Equal(SearchTermsOptions{Limit: 10}))
+		})
+
+		It("reads a limit and whether to exclude stop words", func() {
+			options, err := ParseSearchTermsOptions("25", "true")
+			Expect(err).To(BeNil())
+			Expect(options).To(Equal(SearchTermsOptions{Limit: 25, ExcludeStopWords: true}))
+		})
+
+		for _, invalid := range [][2]string{{"0", ""}, {"101", ""}, {"ten", ""}, {"", "yes"}} {
+			invalid := invalid
+
+			It("rejects "+invalid[0]+invalid[1], func() {
+				_, err := ParseSearchTermsOptions(invalid[0], invalid[1])
+				Expect(err).ToNot(BeNil())
+			})
+		}
+	})
+
+	Describe("NormaliseKeyword", func() {
+		It("ignores case and extra space", func() {
+			Expect(NormaliseKeyword("  Renew   Passport ", false)).To(Equal("renew passport"))
+		})
+
+		It("can leave out stop words", func() {
+			Expect(NormaliseKeyword("How to renew a passport", true)).To(Equal("renew passport"))
+			Expect(NormaliseKeyword("how to", true)).To(Equal(""))
+		})
+	})
+
+	Describe("WithSearchTerms", func() {
+		var statistics Statistics
+
+		BeforeEach(func() {
+			statistics = Statistics{SearchTerms: SearchTerms{
+				searchTerm("renew passport", 30, 20),
+				searchTerm("how to renew passport", 10, 5),
+				searchTerm("passport photo", 10),
+				searchTerm("how to", 25),
+			}}
+		})
+
+		It("shows each term's share of all searches", func() {
+			terms := statistics.WithSearchTerms(SearchTermsOptions{Limit: 2}).SearchTerms
+			Expect(terms).To(HaveLen(2))
+			Expect(terms[0].Keyword).To(Equal("renew passport"))
+			Expect(terms[0].PercentageOfTotal).To(Equal(50.0))
+			Expect(terms[1].Keyword).To(Equal("how to renew passport"))
+			Expect(terms[1].PercentageOfTotal).To(Equal(15.0))
+		})
+
+		It("merges terms that are the same without stop words", func() {
+			terms := statistics.WithSearchTerms(SearchTermsOptions{Limit: 10, ExcludeStopWords: true}).SearchTerms
+			Expect(terms).To(HaveLen(2))
+			Expect(terms[0].Keyword).To(Equal("renew passport"))
+			Expect(terms[0].TotalSearches).To(Equal(65))
+			Expect(terms[0].PercentageOfTotal).To(Equal(65.0))
+			Expect(terms[0].Searches).To(Equal(daily("", 40, 25)))
+			Expect(terms[1].Keyword).To(Equal("passport photo"))
+		})
+
+		It("leaves the statistics it was called on alone", func() {
+			statistics.WithSearchTerms(SearchTermsOptions{Limit: 1, ExcludeStopWords: true})
+			Expect(statistics.SearchTerms).To(HaveLen(4))
+			Expect(statistics.SearchTerms[0].PercentageOfTotal).To(BeZero())
+		})
+	})
+
+	It("are merged when their keywords differ only in case and space", func() {
+		server := ghttp.NewServer()
+		defer server.Close()
+
+		server.RouteToHandler("GET", "/data/govuk-info/search-terms", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("group_by") != "searchKeyword" {
+				ghttp.RespondWith(http.StatusOK, `{"data": []}`)(w, r)
+				return
+			}
+			ghttp.RespondWith(http.StatusOK, `{"data": [
+				{"searchKeyword": "Passport", "searchUniques:sum": 3,
+				 "values": [{"_start_at": "2014-09-02T00:00:00+00:00", "searchUniques:sum": 3}]},
+				{"searchKeyword": "passport ", "searchUniques:sum": 4,
+				 "values": [{"_start_at": "2014-09-02T00:00:00+00:00", "searchUniques:sum": 4}]},
+				{"searchKeyword": "visa", "searchUniques:sum": 5,
+				 "values": [{"_start_at": "2014-09-02T00:00:00+00:00", "searchUniques:sum": 5}]}
+			]}`)(w, r)
+		})
+		for _, dataset := range []string{"page-statistics", "page-contacts"} {
+			server.RouteToHandler("GET", "/data/govuk-info/"+dataset, ghttp.RespondWith(http.StatusOK, `{"data": []}`))
+		}
+
+		client := performanceclient.NewDataClient(server.URL(), logrus.New())
+		statistics, err := SlugStatistics(context.Background(), client, "/foo", false, QueryOptions{})
+		Expect(err).To(BeNil())
+
+		terms := statistics.SearchTerms
+		Expect(terms).To(HaveLen(2))
+		Expect(terms[0].Keyword).To(Equal("passport"))
+		Expect(terms[0].TotalSearches).To(Equal(7))
+		Expect(terms[0].Searches).To(HaveLen(1))
+		Expect(terms[0].Searches[0].Value).To(Equal(7))
+		Expect(terms[0].Searches[0].Timestamp.Equal(time.Date(2014, 9, 2, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+		Expect(terms[1].Keyword).To(Equal("visa"))
+	})
+})
@@ -1,13 +1,15 @@
 package performance_platform
 
 import (
+	"context"
 	"encoding/json"
-	"sort"
-	"sync"
+	"net/http"
 	"time"
 
 	"github.com/alphagov/performanceplatform-client-go"
-	"github.com/jinzhu/now"
+
+	"github.com/alphagov/metadata-api/errgroup"
+	"github.com/alphagov/metadata-api/govuk_path"
 )
 
 type Statistics struct {
@@ -15,14 +17,35 @@ type Statistics struct {
 	Searches       []Statistic `json:"searches"`
 	ProblemReports []Statistic `json:"problem_reports"`
 	SearchTerms    SearchTerms `json:"search_terms"`
+
+	// AllSearchTerms is every search term, before SearchTerms was cut down
+	// to those chosen by WithSearchTerms.
+	AllSearchTerms SearchTerms `json:"-"`
+
+	Summary *StatisticsSummary `json:"summary"`
+
+	// Parts and Unmatched break the statistics for a multipart item down by
+	// part. See WithParts.
+	Parts     []*PartStatistics `json:"parts,omitempty"`
+	Unmatched *PartStatistics   `json:"unmatched,omitempty"`
+
+	// StartAt and EndAt are the period covered. EndAt is exclusive, and the
+	// statistics are complete up to then. Period is how long each value
+	// covers: "day", "week" or "month".
+	StartAt time.Time `json:"-"`
+	EndAt   time.Time `json:"-"`
+	Period  string    `json:"-"`
 }
 
 type SearchTerms []SearchTerm
 
+// SearchTerm is a keyword searched for from a page. PercentageOfTotal is its
+// share of all the searches made from the page.
 type SearchTerm struct {
-	Keyword       string
-	TotalSearches int
-	Searches      []Statistic
+	Keyword           string
+	TotalSearches     int
+	PercentageOfTotal float64
+	Searches          []Statistic
 }
 
 type Statistic struct {
@@ -46,124 +69,94 @@ type SearchUniquesForDate struct {
 	SearchUniques float32   `json:"searchUniques:sum"`
 }
 
-func (terms SearchTerms) Len() int           { return len(terms) }
-func (terms SearchTerms) Swap(i, j int)      { terms[i], terms[j] = terms[j], terms[i] }
-func (terms SearchTerms) Less(i, j int) bool { return terms[i].TotalSearches > terms[j].TotalSearches }
+func (terms SearchTerms) Len() int      { return len(terms) }
+func (terms SearchTerms) Swap(i, j int) { terms[i], terms[j] = terms[j], terms[i] }
+func (terms SearchTerms) Less(i, j int) bool {
+	if terms[i].TotalSearches == terms[j].TotalSearches {
+		return terms[i].Keyword < terms[j].Keyword
+	}
+	return terms[i].TotalSearches > terms[j].TotalSearches
+}
 
-func SlugStatistics(client performanceclient.DataClient, slug string, is_multipart bool) (*Statistics, error) {
-	var pageViews, searches, problemReports []Statistic
-	var searchTerms SearchTerms
-	var waitGroup sync.WaitGroup
+// DatasetError records which Backdrop dataset a statistics query failed for.
+type DatasetError struct {
+	Dataset string
+	Err     error
+}
 
-	errorChannel := make(chan error)
+func (e DatasetError) Error() string {
+	return e.Dataset + ": " + e.Err.Error()
+}
 
-	waitGroup.Add(1)
-	go func() {
-		defer waitGroup.Done()
-
-		query_params := performanceclient.QueryParams{
-			Collect:  []string{"uniquePageviews:sum"},
-			GroupBy:  []string{"pagePath"},
-			Duration: 42,
-			Period:   "day",
-			EndAt:    now.BeginningOfDay().UTC(),
-		}
-		if !is_multipart {
-			query_params.FilterBy = []string{"pagePath:" + slug}
-		} else {
-			query_params.FilterByPrefix = []string{"pagePath:" + slug}
-		}
+// SlugStatistics fetches the statistics for slug, or for every path beneath
+// it if is_multipart, in the window chosen by options. Every search term is
+// included, with normalised keywords. See WithSearchTerms.
+func SlugStatistics(ctx context.Context, client performanceclient.DataClient, slug string, is_multipart bool,
+	options QueryOptions) (*Statistics, error) {
+	if options.Period == "" {
+		options = DefaultQueryOptions()
+	}
 
-		if pageViewsResponse, err := client.Fetch("govuk-info", "page-statistics", query_params); err != nil {
-			errorChannel <- err
-		} else {
-			if pageViews, err = parsePageViews(pageViewsResponse); err != nil {
-				errorChannel <- err
-			}
-		}
-	}()
+	slug, err := govuk_path.Clean(slug)
+	if err != nil {
+		return nil, err
+	}
 
-	waitGroup.Add(1)
-	go func() {
-		defer waitGroup.Done()
+	var pageViews, searches, problemReports []Statistic
+	var searchTerms SearchTerms
 
-		query_params := performanceclient.QueryParams{
-			Collect:  []string{"searchUniques:sum"},
-			GroupBy:  []string{"pagePath"},
-			Duration: 42,
-			Period:   "day",
-			EndAt:    now.BeginningOfDay().UTC(),
-		}
-		if !is_multipart {
-			query_params.FilterBy = []string{"pagePath:" + slug}
-		} else {
-			query_params.FilterByPrefix = []string{"pagePath:" + slug}
-		}
+	group, ctx := errgroup.WithContext(ctx)
 
-		if searchesResponse, err := client.Fetch("govuk-info", "search-terms", query_params); err != nil {
-			errorChannel <- err
-		} else {
-			if searches, err = parseSearches(searchesResponse); err != nil {
-				errorChannel <- err
-			}
+	group.Go(func() error {
+		pageViewsResponse, err := fetch(ctx, client, "page-statistics",
+			options.pathQueryParams("uniquePageviews:sum", slug, is_multipart))
+		if err == nil {
+			pageViews, err = parsePageViews(pageViewsResponse)
 		}
-	}()
-
-	waitGroup.Add(1)
-	go func() {
-		defer waitGroup.Done()
+		return datasetError("page-statistics", err)
+	})
+
+	group.Go(func() error {
+		searchesResponse, err := fetch(ctx, client, "search-terms",
+			options.pathQueryParams("searchUniques:sum", slug, is_multipart))
+		if err == nil {
+			searches, err = parseSearches(searchesResponse)
+		}
+		return datasetError("search-terms", err)
+	})
 
-		if searchTermsResponse, err := client.Fetch("govuk-info", "search-terms", performanceclient.QueryParams{
+	group.Go(func() error {
+		searchTermsResponse, err := fetch(ctx, client, "search-terms", performanceclient.QueryParams{
 			FilterBy: []string{"pagePath:" + slug},
 			GroupBy:  []string{"searchKeyword"},
 			Collect:  []string{"searchUniques:sum"},
-			Duration: 42,
-			Period:   "day",
-			EndAt:    now.BeginningOfDay().UTC(),
-		}); err != nil {
-			errorChannel <- err
-		} else {
-			if searchTerms, err = parseSearchTerms(searchTermsResponse); err != nil {
-				errorChannel <- err
-			} else {
-				sort.Sort(searchTerms)
-				if len(searchTerms) > 10 {
-					searchTerms = searchTerms[0:10]
-				}
-			}
+			Period:   options.Period,
+			StartAt:  options.StartAt,
+			EndAt:    options.EndAt,
+		})
+		if err != nil {
+			return datasetError("search-terms", err)
 		}
-	}()
 
-	waitGroup.Add(1)
-	go func() {
-		defer waitGroup.Done()
-
-		query_params := performanceclient.QueryParams{
-			Collect:  []string{"total:sum"},
-			GroupBy:  []string{"pagePath"},
-			Duration: 42,
-			Period:   "day",
-			EndAt:    now.BeginningOfDay().UTC(),
-		}
-		if !is_multipart {
-			query_params.FilterBy = []string{"pagePath:" + slug}
-		} else {
-			query_params.FilterByPrefix = []string{"pagePath:" + slug}
+		if searchTerms, err = parseSearchTerms(searchTermsResponse); err != nil {
+			return datasetError("search-terms", err)
 		}
 
-		if problemReportsResponse, err := client.Fetch("govuk-info", "page-contacts", query_params); err != nil {
-			errorChannel <- err
-		} else {
-			if problemReports, err = parseProblemReports(problemReportsResponse); err != nil {
-				errorChannel <- err
-			}
-		}
-	}()
+		searchTerms = searchTerms.merged(false)
+		return nil
+	})
 
-	waitGroup.Wait()
+	group.Go(func() error {
+		problemReportsResponse, err := fetch(ctx, client, "page-contacts",
+			options.pathQueryParams("total:sum", slug, is_multipart))
+		if err == nil {
+			problemReports, err = parseProblemReports(problemReportsResponse)
+		}
+		return datasetError("page-contacts", err)
+	})
 
-	if len(errorChannel) > 0 {
-		return nil, <-errorChannel
+	if err := group.Wait(); err != nil {
+		return nil, err
 	}
 
 	return &Statistics{
@@ -171,9 +164,76 @@ func SlugStatistics(client performanceclient.DataClient, slug string, is_multipa
 		Searches:       searches,
 		ProblemReports: problemReports,
 		SearchTerms:    searchTerms,
+		Summary:        summariseStatistics(options.Period, pageViews, searches, problemReports),
+		StartAt:        options.StartAt,
+		EndAt:          options.complete(),
+		Period:         options.Period,
 	}, nil
 }
 
+// pathQueryParams builds a query collecting one value per period per page
+// path, either for slug alone or, for multipart formats, everything beneath
+// it.
+func (options QueryOptions) pathQueryParams(collect, slug string, is_multipart bool) performanceclient.QueryParams {
+	query_params := performanceclient.QueryParams{
+		Collect: []string{collect},
+		GroupBy: []string{"pagePath"},
+		Period:  options.Period,
+		StartAt: options.StartAt,
+		EndAt:   options.EndAt,
+	}
+	if !is_multipart {
+		query_params.FilterBy = []string{"pagePath:" + slug}
+	} else {
+		query_params.FilterByPrefix = []string{"pagePath:" + slug}
+	}
+	return query_params
+}
+
+func datasetError(dataset string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return DatasetError{Dataset: dataset, Err: err}
+}
+
+// WithContext is a performanceclient.Option which binds requests made by a
+// DataClient to ctx, so that they're abandoned when it's cancelled.
+func WithContext(ctx context.Context) performanceclient.Option {
+	return func(req *http.Request, ro *performanceclient.RequestOptions) performanceclient.Option {
+		previous := req.Context()
+		*req = *req.WithContext(ctx)
+		return WithContext(previous)
+	}
+}
+
+// fetch makes a request to the govuk-info data group, returning early if ctx
+// is done before the client responds.
+func fetch(ctx context.Context, client performanceclient.DataClient,
+	dataType string, queryParams performanceclient.QueryParams) (*performanceclient.BackdropResponse, error) {
+	type result struct {
+		response *performanceclient.BackdropResponse
+		err      error
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		response, err := client.Fetch("govuk-info", dataType, queryParams)
+		results <- result{response, err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.response, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func parsePageViews(response *performanceclient.BackdropResponse) ([]Statistic, error) {
 	var datasetsPerPath []struct {
 		Path   string             `json:"pagePath"`
@@ -1,6 +1,7 @@
 package performance_platform_test
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -136,7 +137,7 @@ var _ = Describe("Statistics", func() {
 ]
 }`)))
 
-			statistics, err := SlugStatistics(client, "/foo", false)
+			statistics, err := SlugStatistics(context.Background(), client, "/foo", false, QueryOptions{})
 			Expect(err).To(BeNil())
 			Expect(statistics).ToNot(BeNil())
 			Expect(len(statistics.PageViews)).To(Equal(1))
@@ -170,6 +171,10 @@ var _ = Describe("Statistics", func() {
 			Expect(len(statistics.SearchTerms[0].Searches)).To(Equal(1))
 			Expect(statistics.SearchTerms[0].Searches[0].Value).To(Equal(126))
 			Expect(statistics.SearchTerms[0].Searches[0].Timestamp).To(Equal(searchesTimestamp))
+
+			Expect(statistics.Summary.PageViews.Total).To(Equal(25931))
+			Expect(statistics.Summary.ProblemReports.Total).To(Equal(71))
+			Expect(*statistics.Summary.ProblemReportsPer1000PageViews).To(Equal(2.74))
 		})
 
 	})
@@ -315,7 +320,7 @@ var _ = Describe("Statistics", func() {
 ]
 }`)))
 
-			statistics, err := SlugStatistics(client, "/foo", true)
+			statistics, err := SlugStatistics(context.Background(), client, "/foo", true, QueryOptions{})
 			Expect(err).To(BeNil())
 			Expect(statistics).ToNot(BeNil())
 			Expect(len(statistics.PageViews)).To(Equal(2))
@@ -342,4 +347,101 @@ var _ = Describe("Statistics", func() {
 
 	})
 
+	Describe("SlugStatisticsFailures", func() {
+		var failingDataset, failingGroupBy string
+
+		respond := func(dataset string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				groupBy := r.URL.Query().Get("group_by")
+				if dataset == failingDataset && (failingGroupBy == "" || groupBy == failingGroupBy) {
+					ghttp.RespondWith(http.StatusOK, `{"status": "error", "message": "boom"}`)(w, r)
+					return
+				}
+				ghttp.RespondWith(http.StatusOK, `{"data": []}`)(w, r)
+			}
+		}
+
+		BeforeEach(func() {
+			failingDataset, failingGroupBy = "", ""
+			for _, dataset := range []string{"page-statistics", "search-terms", "page-contacts"} {
+				server.RouteToHandler("GET", "/data/govuk-info/"+dataset, respond(dataset))
+			}
+		})
+
+		It("succeeds when no dataset fails", func() {
+			statistics, err := SlugStatistics(context.Background(), client, "/foo", false, QueryOptions{})
+			Expect(err).To(BeNil())
+			Expect(statistics).ToNot(BeNil())
+		})
+
+		for _, failure := range []struct{ dataset, groupBy string }{
+			{"page-statistics", ""},
+			{"search-terms", "pagePath"},
+			{"search-terms", "searchKeyword"},
+			{"page-contacts", ""},
+		} {
+			failure := failure
+
+			It("reports a failure of "+failure.dataset+" grouped by "+failure.groupBy, func() {
+				failingDataset, failingGroupBy = failure.dataset, failure.groupBy
+
+				statistics, err := SlugStatistics(context.Background(), client, "/foo", true, QueryOptions{})
+				Expect(statistics).To(BeNil())
+
+				datasetErr, ok := err.(DatasetError)
+				Expect(ok).To(BeTrue())
+				Expect(datasetErr.Dataset).To(Equal(failure.dataset))
+				Expect(datasetErr.Error()).To(Equal(failure.dataset + ": boom"))
+			})
+		}
+
+		It("returns the first failure without waiting for slower datasets", func() {
+			release := make(chan struct{})
+			defer close(release)
+
+			server.RouteToHandler("GET", "/data/govuk-info/page-statistics",
+				func(w http.ResponseWriter, r *http.Request) {
+					<-release
+				})
+			failingDataset = "page-contacts"
+
+			start := time.Now()
+			_, err := SlugStatistics(context.Background(), client, "/foo", false, QueryOptions{})
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+			datasetErr, ok := err.(DatasetError)
+			Expect(ok).To(BeTrue())
+			Expect(datasetErr.Dataset).To(Equal("page-contacts"))
+		})
+
+		It("filters by the cleaned path, and rejects paths that escape", func() {
+			var filters []string
+			server.RouteToHandler("GET", "/data/govuk-info/page-statistics", func(w http.ResponseWriter, r *http.Request) {
+				filters = append(filters, r.URL.Query().Get("filter_by"))
+				w.Write([]byte(`{"data": []}`))
+			})
+			server.RouteToHandler("GET", "/data/govuk-info/search-terms", ghttp.RespondWith(http.StatusOK, `{"data": []}`))
+			server.RouteToHandler("GET", "/data/govuk-info/page-contacts", ghttp.RespondWith(http.StatusOK, `{"data": []}`))
+
+			_, err := SlugStatistics(context.Background(), client, "foo//bar/", false, QueryOptions{})
+			Expect(err).To(BeNil())
+			Expect(filters).To(Equal([]string{"pagePath:/foo/bar"}))
+
+			_, err = SlugStatistics(context.Background(), client, "/foo/../bar", false, QueryOptions{})
+			Expect(err).ToNot(BeNil())
+			Expect(filters).To(HaveLen(1))
+		})
+
+		It("gives up when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := SlugStatistics(ctx, client, "/foo", false, QueryOptions{})
+
+			datasetErr, ok := err.(DatasetError)
+			Expect(ok).To(BeTrue())
+			Expect(datasetErr.Err).To(Equal(context.Canceled))
+		})
+	})
+
 })
@@ -0,0 +1,124 @@
+package performance_platform
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// StatisticsSummary totals up each series of Statistics, so that clients
+// don't each have to.
+type StatisticsSummary struct {
+	PageViews      *Summary `json:"page_views"`
+	Searches       *Summary `json:"searches"`
+	ProblemReports *Summary `json:"problem_reports"`
+
+	// ProblemReportsPer1000PageViews is nil if there were no page views.
+	ProblemReportsPer1000PageViews *float64 `json:"problem_reports_per_1000_page_views"`
+}
+
+// Summary describes one series. Values for every path on the same date are
+// added together first, so that Mean, Median, Min and Max are of the totals
+// per period. For daily values, WeekOnWeek compares the last week with the
+// one before; it's nil, and left out, for weekly or monthly values.
+type Summary struct {
+	Total  int     `json:"total"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+
+	*WeekOnWeek
+}
+
+// WeekOnWeek compares the seven days up to the most recent value with the
+// seven before. PercentageChange is nil if there was nothing in the seven
+// days before.
+type WeekOnWeek struct {
+	Last7Days        int      `json:"last_7_days"`
+	Previous7Days    int      `json:"previous_7_days"`
+	PercentageChange *float64 `json:"percentage_change"`
+}
+
+func summariseStatistics(period string, pageViews, searches, problemReports []Statistic) *StatisticsSummary {
+	summary := &StatisticsSummary{
+		PageViews:      Summarise(pageViews, period),
+		Searches:       Summarise(searches, period),
+		ProblemReports: Summarise(problemReports, period),
+	}
+
+	if summary.PageViews.Total > 0 {
+		rate := round(1000*float64(summary.ProblemReports.Total)/float64(summary.PageViews.Total), 2)
+		summary.ProblemReportsPer1000PageViews = &rate
+	}
+
+	return summary
+}
+
+// Summarise computes the Summary of a series of values per period, which
+// is "day", "week" or "month". An empty period is "day".
+func Summarise(series []Statistic, period string) *Summary {
+	summary := &Summary{}
+	if period == "" || period == "day" {
+		summary.WeekOnWeek = &WeekOnWeek{}
+	}
+
+	totals := make(map[time.Time]int)
+	var latest time.Time
+	for _, statistic := range series {
+		totals[statistic.Timestamp] += statistic.Value
+		summary.Total += statistic.Value
+		if statistic.Timestamp.After(latest) {
+			latest = statistic.Timestamp
+		}
+	}
+
+	if len(totals) == 0 {
+		return summary
+	}
+
+	values := make([]int, 0, len(totals))
+	for _, total := range totals {
+		values = append(values, total)
+	}
+	sort.Ints(values)
+
+	summary.Mean = round(float64(summary.Total)/float64(len(values)), 2)
+	summary.Min, summary.Max = values[0], values[len(values)-1]
+
+	middle := len(values) / 2
+	if len(values)%2 == 1 {
+		summary.Median = float64(values[middle])
+	} else {
+		summary.Median = float64(values[middle-1]+values[middle]) / 2
+	}
+
+	if summary.WeekOnWeek != nil {
+		summary.WeekOnWeek.compare(totals, latest)
+	}
+
+	return summary
+}
+
+// compare fills in the week on week comparison of totals, the values for
+// each day, up to latest.
+func (week *WeekOnWeek) compare(totals map[time.Time]int, latest time.Time) {
+	weekAgo, fortnightAgo := latest.AddDate(0, 0, -7), latest.AddDate(0, 0, -14)
+	for timestamp, total := range totals {
+		if timestamp.After(weekAgo) {
+			week.Last7Days += total
+		} else if timestamp.After(fortnightAgo) {
+			week.Previous7Days += total
+		}
+	}
+
+	if week.Previous7Days > 0 {
+		change := round(100*float64(week.Last7Days-week.Previous7Days)/float64(week.Previous7Days), 1)
+		week.PercentageChange = &change
+	}
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Floor(value*scale+0.5) / scale
+}
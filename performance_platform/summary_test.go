@@ -0,0 +1,70 @@
+package performance_platform_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/alphagov/metadata-api/performance_platform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func daily(path string, values ...int) []Statistic {
+	start := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	series := []Statistic{}
+	for i, value := range values {
+		series = append(series, Statistic{Path: path, Timestamp: start.AddDate(0, 0, i), Value: value})
+	}
+	return series
+}
+
+var _ = Describe("Summarise", func() {
+	It("describes an empty series as all zeroes", func() {
+		Expect(Summarise([]Statistic{}, "day")).To(Equal(&Summary{WeekOnWeek: &WeekOnWeek{}}))
+	})
+
+	It("totals the values for each date before describing them", func() {
+		series := append(daily("/foo", 1, 2, 3), daily("/foo/part", 10, 0, 1)...)
+
+		summary := Summarise(series, "day")
+		Expect(summary.Total).To(Equal(17))
+		Expect(summary.Mean).To(Equal(5.67))
+		Expect(summary.Median).To(Equal(4.0))
+		Expect(summary.Min).To(Equal(2))
+		Expect(summary.Max).To(Equal(11))
+	})
+
+	It("takes the median of an even number of values as the mean of the middle two", func() {
+		Expect(Summarise(daily("/foo", 4, 1, 3, 10), "day").Median).To(Equal(3.5))
+	})
+
+	It("compares the last seven days with the seven before", func() {
+		summary := Summarise(daily("/foo", 100, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2), "day")
+		Expect(summary.Previous7Days).To(Equal(7))
+		Expect(summary.Last7Days).To(Equal(14))
+		Expect(*summary.PercentageChange).To(Equal(100.0))
+	})
+
+	It("leaves the percentage change out when there's nothing to compare with", func() {
+		summary := Summarise(daily("/foo", 1, 2), "")
+		Expect(summary.Last7Days).To(Equal(3))
+		Expect(summary.PercentageChange).To(BeNil())
+	})
+
+	It("only compares weeks for daily values", func() {
+		monthly := []Statistic{
+			{Path: "/foo", Timestamp: time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC), Value: 100},
+			{Path: "/foo", Timestamp: time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC), Value: 120},
+		}
+
+		summary := Summarise(monthly, "month")
+		Expect(summary.Total).To(Equal(220))
+		Expect(summary.WeekOnWeek).To(BeNil())
+
+		body, err := json.Marshal(summary)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(Equal(`{"total":220,"mean":110,"median":110,"min":100,"max":120}`))
+	})
+})
@@ -0,0 +1,133 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/alphagov/metadata-api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func redirectItem(path, destination string) string {
+	return fmt.Sprintf(`{"base_path": %q, "schema_name": "redirect", "document_type": "redirect",
+		"redirects": [{"path": %q, "type": "exact", "destination": %q}]}`, path, path, destination)
+}
+
+var _ = Describe("Moved and removed content", func() {
+	var server, performanceAPI *httptest.Server
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	get := func(path string) (*http.Response, map[string]interface{}) {
+		response, err := client.Get(server.URL + path)
+		Expect(err).To(BeNil())
+		return response, readJSONResponse(response)
+	}
+
+	BeforeEach(func() {
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":[]}`)
+		})
+
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, pathJSONRequest{
+			"/one":   contentItem("/one"),
+			"/old":   redirectItem("/old", "/one"),
+			"/older": redirectItem("/older", "/old"),
+			"/away":  redirectItem("/away", "https://www.example.com/away"),
+			"/loop":  redirectItem("/loop", "/loop"),
+			"/gone":  `{"base_path": "/gone", "schema_name": "gone", "document_type": "gone"}`,
+			"/withdrawn": `{"base_path": "/withdrawn", "content_id": "id", "title": "Withdrawn", "document_type": "answer",
+				"withdrawn_notice": {"explanation": "This is out of date", "withdrawn_at": "2017-05-02T10:15:00Z"}}`,
+		}, &Config{BatchMaxPaths: 3, BatchConcurrency: 3})
+		server = httptest.NewServer(NewRouter(fetcher))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		performanceAPI.Close()
+	})
+
+	It("redirects to the metadata for where content has moved to", func() {
+		response, body := get("/v1/info/old?period=day")
+		Expect(response.StatusCode).To(Equal(http.StatusMovedPermanently))
+		Expect(response.Header.Get("Location")).To(Equal("/v1/info/one?period=day"))
+		Expect(body["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "moved permanently", "location": "/one",
+		}))
+
+		response, body = get("/v2/info/old")
+		Expect(response.StatusCode).To(Equal(http.StatusPermanentRedirect))
+		Expect(response.Header.Get("Location")).To(Equal("/v2/info/one"))
+		Expect(body["response_info"]).To(Equal(map[string]interface{}{
+			"status": "moved permanently", "location": "/one",
+		}))
+
+		response, _ = get("/info/old")
+		Expect(response.Header.Get("Location")).To(Equal("/info/one"))
+	})
+
+	It("redirects to destinations that aren't on GOV.UK", func() {
+		response, _ := get("/v1/info/away")
+		Expect(response.StatusCode).To(Equal(http.StatusMovedPermanently))
+		Expect(response.Header.Get("Location")).To(Equal("https://www.example.com/away"))
+	})
+
+	It("follows redirects when asked to", func() {
+		response, body := get("/v1/info/older?follow_redirects=true")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(body["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "ok", "redirected_from": "/older",
+		}))
+		Expect(body["artefact"].(map[string]interface{})["web_url"]).To(HaveSuffix("/one"))
+
+		response, body = get("/v1/info/loop?follow_redirects=true")
+		Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(body["_response_info"]).To(Equal(map[string]interface{}{"status": "Artefact: too many redirects"}))
+
+		response, _ = get("/v1/info/away?follow_redirects=true")
+		Expect(response.StatusCode).To(Equal(http.StatusMovedPermanently))
+	})
+
+	It("responds 410 for content that has gone", func() {
+		response, body := get("/v2/info/gone")
+		Expect(response.StatusCode).To(Equal(http.StatusGone))
+		Expect(body["response_info"]).To(Equal(map[string]interface{}{"status": "gone"}))
+	})
+
+	It("includes the notice for withdrawn content", func() {
+		response, body := get("/v2/info/withdrawn")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(body["artefact"].(map[string]interface{})["withdrawn_notice"]).To(Equal(map[string]interface{}{
+			"explanation": "This is out of date", "withdrawn_at": "2017-05-02T10:15:00Z",
+		}))
+
+		_, body = get("/v1/info/withdrawn")
+		Expect(body["artefact"]).ToNot(HaveKey("withdrawn_notice"))
+
+		_, body = get("/v2/info/one")
+		Expect(body["artefact"]).ToNot(HaveKey("withdrawn_notice"))
+	})
+
+	It("says where moved content is in batches", func() {
+		response, err := http.Post(server.URL+"/v1/info/batch", "application/json",
+			strings.NewReader(`["/old", "/gone"]`))
+		Expect(err).To(BeNil())
+
+		var results map[string]map[string]interface{}
+		body, _ := readResponseBody(response)
+		Expect(json.Unmarshal([]byte(body), &results)).To(Succeed())
+		Expect(results["/old"]["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "moved permanently", "location": "/one", "status_code": 301.0,
+		}))
+		Expect(results["/gone"]["_response_info"]).To(Equal(map[string]interface{}{
+			"status": "gone", "status_code": 410.0,
+		}))
+	})
+})
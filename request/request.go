@@ -5,34 +5,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
 var (
 	NotFoundError error = errors.New("not found")
-)
-
-func NewRequest(url, bearerToken string) (*http.Response, error) {
-	client := &http.Client{}
-
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	request.Header.Add("Authorization", "Bearer "+bearerToken)
-	request.Header.Add("Accept", "application/json")
 
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, err
-	}
-
-	if response.StatusCode == http.StatusNotFound {
-		return nil, NotFoundError
-	}
-
-	return response, err
-}
+	// DefaultTimeout bounds any single request, as a backstop for callers
+	// that don't set a deadline on their context.
+	DefaultTimeout = 30 * time.Second
+)
 
 func ReadResponseBody(response *http.Response) (string, error) {
 	body, err := ioutil.ReadAll(response.Body)
@@ -0,0 +1,280 @@
+// Package schema describes the JSON that Go types are encoded as, following
+// the rules of encoding/json, as JSON Schema.
+package schema
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema is the subset of JSON Schema needed to describe encoded Go values.
+// Type is a string, or a list of them for values that may also be null.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Definitions are the schemas of named struct types, by name.
+type Definitions map[string]*Schema
+
+// Generator builds schemas for Go types. Each named struct type is described
+// once, in Definitions, and referred to everywhere else with a $ref starting
+// with the generator's prefix.
+type Generator struct {
+	Definitions Definitions
+
+	prefix   string
+	names    map[reflect.Type]string
+	concrete map[reflect.Type]map[string]reflect.Type
+}
+
+// NewGenerator returns a Generator whose references start with prefix, such
+// as "#/definitions/".
+func NewGenerator(prefix string) *Generator {
+	return &Generator{
+		Definitions: make(Definitions),
+		prefix:      prefix,
+		names:       make(map[reflect.Type]string),
+		concrete:    make(map[reflect.Type]map[string]reflect.Type),
+	}
+}
+
+// Concrete describes the field of owner that's encoded as name, which is an
+// interface, as always holding values of the same type as value.
+func (g *Generator) Concrete(owner interface{}, name string, value interface{}) {
+	ownerType := reflect.TypeOf(owner)
+	if g.concrete[ownerType] == nil {
+		g.concrete[ownerType] = make(map[string]reflect.Type)
+	}
+	g.concrete[ownerType][name] = reflect.TypeOf(value)
+}
+
+// Reflect returns the schema for values of the same type as v.
+func (g *Generator) Reflect(v interface{}) *Schema {
+	return g.reflect(reflect.TypeOf(v))
+}
+
+func (g *Generator) reflect(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.reflect(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable(&Schema{Type: "string", Format: "byte"})
+		}
+		return nullable(&Schema{Type: "array", Items: g.reflect(t.Elem())})
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.reflect(t.Elem())}
+	case reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: g.reflect(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &Schema{Ref: g.prefix + g.define(t)}
+	}
+
+	// Interfaces, and anything else, could be encoded as any value.
+	return &Schema{}
+}
+
+// define adds the schema for a named struct type to Definitions if it isn't
+// there already, returning its name. That's the type's own name, unless
+// another type in a different package has it.
+func (g *Generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.Definitions[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+
+	g.names[t] = name
+	g.Definitions[name] = &Schema{}
+	*g.Definitions[name] = *g.object(t)
+	return name
+}
+
+// object describes a struct type as an object with a property for each
+// field encoding/json would encode, all of which are required unless they're
+// omitempty or promoted from an embedded pointer, which may be nil. Other
+// properties are allowed, so that clients keep working as fields are added.
+func (g *Generator) object(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+		Required:   []string{},
+	}
+
+	for _, field := range dominantFields(fields(t, 0)) {
+		fieldType := field.Type
+		if concrete, ok := g.concrete[field.owner][field.name]; ok {
+			fieldType = concrete
+		}
+
+		schema.Properties[field.name] = g.reflect(fieldType)
+		if !field.omitEmpty {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+
+	return schema
+}
+
+// nullable allows null as well as whatever schema allows.
+func nullable(schema *Schema) *Schema {
+	switch {
+	case schema.Ref != "":
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	case schema.AnyOf != nil:
+		for _, option := range schema.AnyOf {
+			if option.Type == "null" {
+				return schema
+			}
+		}
+		return &Schema{AnyOf: append(schema.AnyOf, &Schema{Type: "null"})}
+	}
+
+	if t, ok := schema.Type.(string); ok {
+		schema.Type = []string{t, "null"}
+	}
+	return schema
+}
+
+type field struct {
+	reflect.StructField
+
+	owner     reflect.Type
+	name      string
+	omitEmpty bool
+	tagged    bool
+	depth     int
+}
+
+// fields lists the fields of a struct type that encoding/json could encode,
+// including those promoted from embedded structs, in order. Fields promoted
+// from an embedded pointer are marked omitempty, as encoding/json leaves
+// them all out when it's nil.
+func fields(t reflect.Type, depth int) []field {
+	var result []field
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		tag := structField.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma+1:]
+		}
+
+		if structField.Anonymous && name == "" {
+			embedded, pointer := structField.Type, false
+			if embedded.Kind() == reflect.Ptr {
+				embedded, pointer = embedded.Elem(), true
+			}
+			if embedded.Kind() == reflect.Struct {
+				promoted := fields(embedded, depth+1)
+				for i := range promoted {
+					promoted[i].omitEmpty = promoted[i].omitEmpty || pointer
+				}
+				result = append(result, promoted...)
+				continue
+			}
+		}
+
+		if structField.PkgPath != "" {
+			continue
+		}
+
+		f := field{
+			StructField: structField,
+			owner:       t,
+			name:        name,
+			omitEmpty:   strings.Contains(","+options+",", ",omitempty,"),
+			tagged:      name != "",
+			depth:       depth,
+		}
+		if !f.tagged {
+			f.name = structField.Name
+		}
+		result = append(result, f)
+	}
+
+	return result
+}
+
+// dominantFields drops the fields that encoding/json wouldn't encode because
+// another with the same name is less deeply embedded, or equally deep and
+// the only one tagged with the name. If there's no such field, none with the
+// name are encoded.
+func dominantFields(all []field) []field {
+	byName := make(map[string][]int)
+	for i, f := range all {
+		byName[f.name] = append(byName[f.name], i)
+	}
+
+	var result []field
+	for i, f := range all {
+		if dominant, ok := dominantField(all, byName[f.name]); ok && dominant == i {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// dominantField picks which of the fields at candidates, which share a name,
+// is encoded.
+func dominantField(all []field, candidates []int) (int, bool) {
+	depth := all[candidates[0]].depth
+	for _, i := range candidates {
+		if all[i].depth < depth {
+			depth = all[i].depth
+		}
+	}
+
+	var shallowest, tagged []int
+	for _, i := range candidates {
+		if all[i].depth == depth {
+			shallowest = append(shallowest, i)
+			if all[i].tagged {
+				tagged = append(tagged, i)
+			}
+		}
+	}
+
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return 0, false
+}
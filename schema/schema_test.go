@@ -0,0 +1,148 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/alphagov/metadata-api/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Inner struct {
+	Name  string `json:"name"`
+	Shown string `json:"shown"`
+}
+
+type Outer struct {
+	*Inner
+	Shown     int `json:"shown"`
+	Untagged  bool
+	Optional  []string            `json:"optional,omitempty"`
+	When      *time.Time          `json:"when"`
+	Counts    map[string]int      `json:"counts"`
+	Anything  interface{}         `json:"anything"`
+	Recursive *Outer              `json:"recursive"`
+	Hidden    string              `json:"-"`
+	Anonymous struct{ X float64 } `json:"anonymous"`
+	private   string
+}
+
+func decode(body string) interface{} {
+	var value interface{}
+	Expect(json.Unmarshal([]byte(body), &value)).To(Succeed())
+	return value
+}
+
+var _ = Describe("Generator", func() {
+	var (
+		generator *Generator
+		outer     *Schema
+	)
+
+	BeforeEach(func() {
+		generator = NewGenerator("#/definitions/")
+		outer = generator.Reflect(Outer{})
+	})
+
+	It("refers to named structs by name", func() {
+		Expect(outer).To(Equal(&Schema{Ref: "#/definitions/Outer"}))
+		Expect(generator.Definitions).To(HaveLen(1))
+	})
+
+	It("describes the fields encoding/json encodes", func() {
+		definition := generator.Definitions["Outer"]
+
+		Expect(definition.Type).To(Equal("object"))
+		Expect(definition.AdditionalProperties).To(BeNil())
+		Expect(definition.Required).To(Equal([]string{
+			"shown", "Untagged", "when", "counts", "anything", "recursive", "anonymous",
+		}))
+		Expect(definition.Properties).To(Equal(map[string]*Schema{
+			"name":      {Type: "string"},
+			"shown":     {Type: "integer"},
+			"Untagged":  {Type: "boolean"},
+			"optional":  {Type: []string{"array", "null"}, Items: &Schema{Type: "string"}},
+			"when":      {Type: []string{"string", "null"}, Format: "date-time"},
+			"counts":    {Type: []string{"object", "null"}, AdditionalProperties: &Schema{Type: "integer"}},
+			"anything":  {},
+			"recursive": {AnyOf: []*Schema{{Ref: "#/definitions/Outer"}, {Type: "null"}}},
+			"anonymous": {
+				Type:       "object",
+				Properties: map[string]*Schema{"X": {Type: "number"}},
+				Required:   []string{"X"},
+			},
+		}))
+	})
+
+	It("describes interfaces as the concrete type they hold", func() {
+		generator = NewGenerator("#/definitions/")
+		generator.Concrete(Outer{}, "anything", Inner{})
+		generator.Reflect(Outer{})
+
+		Expect(generator.Definitions["Outer"].Properties["anything"]).To(Equal(&Schema{Ref: "#/definitions/Inner"}))
+	})
+
+	Describe("Validate", func() {
+		valid := `{"name": "a", "shown": 1, "Untagged": true, "when": "2017-03-01T00:00:00Z", "counts": {"b": 2},
+			"anything": [1, "c"], "recursive": null, "anonymous": {"X": 1.5}}`
+
+		It("accepts what the type is encoded as", func() {
+			bytes, _ := json.Marshal(Outer{Inner: &Inner{}, Recursive: &Outer{}})
+			Expect(generator.Definitions.Validate(outer, decode(string(bytes)))).To(Succeed())
+			Expect(generator.Definitions.Validate(outer, decode(valid))).To(Succeed())
+		})
+
+		It("allows properties the type doesn't have", func() {
+			value := decode(valid).(map[string]interface{})
+			value["extra"] = 1.0
+			value["anonymous"].(map[string]interface{})["Y"] = 2.0
+
+			Expect(generator.Definitions.Validate(outer, value)).To(Succeed())
+		})
+
+		It("rejects them strictly", func() {
+			value := decode(valid).(map[string]interface{})
+			value["anonymous"].(map[string]interface{})["Y"] = 2.0
+
+			Expect(generator.Definitions.Strict().Validate(outer, value)).To(
+				MatchError("$.anonymous: has unexpected property Y"))
+			Expect(generator.Definitions["Outer"].Properties["anonymous"].AdditionalProperties).To(BeNil())
+		})
+
+		It("rejects values of the wrong type", func() {
+			Expect(generator.Definitions.Validate(outer, decode(`[]`))).To(MatchError("$: is array, not object"))
+		})
+
+		type invalidCase struct {
+			description string
+			change      func(value map[string]interface{})
+			expected    string
+		}
+
+		for _, c := range []invalidCase{
+			{"missing properties", func(value map[string]interface{}) { delete(value, "shown") },
+				"$: is missing shown"},
+			{"fractional integers", func(value map[string]interface{}) { value["shown"] = 1.5 },
+				"$.shown: is number, not integer"},
+			{"malformed dates", func(value map[string]interface{}) { value["when"] = "yesterday" },
+				"$.when: is not a date-time"},
+			{"wrong map values", func(value map[string]interface{}) { value["counts"] = decode(`{"b": "two"}`) },
+				"$.counts.b: is string, not integer"},
+			{"invalid references", func(value map[string]interface{}) { value["recursive"] = decode(`{"name": 1}`) },
+				"$.recursive: is missing shown"},
+		} {
+			c := c
+
+			It("rejects "+c.description, func() {
+				value := decode(valid).(map[string]interface{})
+				c.change(value)
+
+				err := generator.Definitions.Validate(outer, value)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix(c.expected))
+			})
+		}
+	})
+})
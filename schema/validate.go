@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError says where in a value, and why, it doesn't match a schema.
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Reason
+}
+
+// Validate checks that value, as decoded from JSON into an interface{},
+// matches schema, whose references are to definitions. Only the parts of
+// JSON Schema that a Generator uses are understood.
+func (definitions Definitions) Validate(schema *Schema, value interface{}) error {
+	return definitions.validate("$", schema, value)
+}
+
+// Strict is a copy of definitions in which objects with properties have
+// no others, to check that a value has nothing the schema doesn't describe.
+func (definitions Definitions) Strict() Definitions {
+	strict := make(Definitions, len(definitions))
+	for name, definition := range definitions {
+		strict[name] = strictSchema(definition)
+	}
+	return strict
+}
+
+func strictSchema(schema *Schema) *Schema {
+	if schema == nil {
+		return nil
+	}
+
+	strict := *schema
+	if schema.Properties != nil {
+		strict.Properties = make(map[string]*Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			strict.Properties[name] = strictSchema(property)
+		}
+		if strict.AdditionalProperties == nil {
+			strict.AdditionalProperties = false
+		}
+	}
+	if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+		strict.AdditionalProperties = strictSchema(additional)
+	}
+	strict.Items = strictSchema(schema.Items)
+	if schema.AnyOf != nil {
+		strict.AnyOf = make([]*Schema, len(schema.AnyOf))
+		for i, option := range schema.AnyOf {
+			strict.AnyOf[i] = strictSchema(option)
+		}
+	}
+	return &strict
+}
+
+func (definitions Definitions) validate(path string, schema *Schema, value interface{}) error {
+	if schema.Ref != "" {
+		name := schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+		definition, ok := definitions[name]
+		if !ok {
+			return ValidationError{path, "refers to unknown schema " + schema.Ref}
+		}
+		return definitions.validate(path, definition, value)
+	}
+
+	if schema.AnyOf != nil {
+		var firstErr error
+		for _, option := range schema.AnyOf {
+			err := definitions.validate(path, option, value)
+			if err == nil {
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	if schema.Type == nil {
+		return nil
+	}
+
+	types, ok := schema.Type.([]string)
+	if !ok {
+		types = []string{schema.Type.(string)}
+	}
+
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return definitions.validateValue(path, schema, value)
+		}
+	}
+	return ValidationError{path, fmt.Sprintf("is %s, not %s", actual, strings.Join(types, " or "))}
+}
+
+func (definitions Definitions) validateValue(path string, schema *Schema, value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return ValidationError{path, "is not a date-time: " + err.Error()}
+			}
+		}
+
+	case []interface{}:
+		if schema.Items == nil {
+			return nil
+		}
+		for i, item := range value {
+			if err := definitions.validate(fmt.Sprintf("%s[%d]", path, i), schema.Items, item); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return ValidationError{path, "is missing " + name}
+			}
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				switch additional := schema.AdditionalProperties.(type) {
+				case *Schema:
+					property = additional
+				case bool:
+					if !additional {
+						return ValidationError{path, "has unexpected property " + key}
+					}
+				}
+			}
+			if property == nil {
+				continue
+			}
+
+			if err := definitions.validate(path+"."+key, property, value[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// typeOf is the JSON Schema type of a value decoded from JSON.
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+type lastGoodMetadata struct {
+	metadata  *Metadata
+	fetchedAt time.Time
+}
+
+func lastGoodKey(slug string, options InfoOptions) string {
+	return slug + "|" + options.key()
+}
+
+// Stale returns the last complete Metadata fetched for slug with options,
+// marked as stale, if there is one and it's no older than the StaleTTL.
+func (fetcher *Fetcher) Stale(slug string, options InfoOptions) (*Metadata, bool) {
+	cached, ok := fetcher.lastGood.Get(lastGoodKey(slug, options))
+	if !ok {
+		return nil, false
+	}
+
+	lastGood := cached.(*lastGoodMetadata)
+	stale := *lastGood.metadata
+	stale.ResponseInfo = &ResponseInfo{
+		Status:    "ok",
+		Stale:     true,
+		FetchedAt: &lastGood.fetchedAt,
+	}
+
+	return &stale, true
+}
+
+// RefreshInBackground fetches the Metadata for slug with options again
+// without blocking, so that a later request can be given fresh data. The
+// fetch waits for the StaleRefreshInterval first, so that an upstream that
+// has just failed isn't asked again straight away, and it does nothing if a
+// refresh for the same Metadata is already waiting or running.
+func (fetcher *Fetcher) RefreshInBackground(slug string, options InfoOptions) {
+	key := lastGoodKey(slug, options)
+
+	fetcher.mutex.Lock()
+	if fetcher.refreshing[key] {
+		fetcher.mutex.Unlock()
+		return
+	}
+	fetcher.refreshing[key] = true
+	fetcher.refreshes.Add(1)
+	fetcher.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			fetcher.mutex.Lock()
+			delete(fetcher.refreshing, key)
+			fetcher.mutex.Unlock()
+			fetcher.refreshes.Done()
+		}()
+
+		time.Sleep(fetcher.config.StaleRefreshInterval)
+
+		ctx, cancel := withTimeout(context.Background(), fetcher.config.RequestTimeout)
+		defer cancel()
+
+		fetcher.Metadata(ctx, slug, options)
+	}()
+}
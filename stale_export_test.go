@@ -0,0 +1,6 @@
+package main
+
+// WaitForRefreshes blocks until every background refresh has finished.
+func (fetcher *Fetcher) WaitForRefreshes() {
+	fetcher.refreshes.Wait()
+}
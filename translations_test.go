@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/alphagov/metadata-api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func translatedItem(path, locale, title string) string {
+	return fmt.Sprintf(`{"base_path": %q, "content_id": "id", "title": %q, "document_type": "answer",
+		"locale": %q, "links": {"available_translations": [
+			{"content_id": "id", "locale": "en", "title": "Volunteer", "base_path": "/volunteer"},
+			{"content_id": "id", "locale": "cy", "title": "Gwirfoddoli", "base_path": "/volunteer.cy"}]}}`,
+		path, title, locale)
+}
+
+var _ = Describe("Translations", func() {
+	var server, performanceAPI *httptest.Server
+
+	BeforeEach(func() {
+		pageViews := map[string]int{"/volunteer": 100, "/volunteer.cy": 7}
+
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Query().Get("filter_by"), "pagePath:")
+			if !strings.HasSuffix(r.URL.Path, "page-statistics") || pageViews[path] == 0 {
+				fmt.Fprintln(w, `{"data":[]}`)
+				return
+			}
+
+			fmt.Fprintf(w, `{"data": [{"pagePath": %q, "values": [
+				{"_start_at": "2014-07-03T00:00:00+00:00", "uniquePageviews:sum": %d}]}]}`, path, pageViews[path])
+		})
+
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, pathJSONRequest{
+			"/volunteer":    translatedItem("/volunteer", "en", "Volunteer"),
+			"/volunteer.cy": translatedItem("/volunteer.cy", "cy", "Gwirfoddoli"),
+		}, &Config{})
+		server = httptest.NewServer(NewRouter(fetcher))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		performanceAPI.Close()
+	})
+
+	It("lists the available translations", func() {
+		_, metadata := getJSON(server.URL + "/v2/info/volunteer")
+		artefact := metadata["artefact"].(map[string]interface{})
+		Expect(artefact["locale"]).To(Equal("en"))
+		Expect(artefact["available_translations"]).To(HaveLen(2))
+		Expect(artefact["available_translations"].([]interface{})[1]).To(Equal(map[string]interface{}{
+			"locale": "cy", "title": "Gwirfoddoli", "web_url": "/volunteer.cy",
+		}))
+	})
+
+	It("responds for the translation in the locale asked for", func() {
+		response, metadata := getJSON(server.URL + "/v1/info/volunteer?locale=cy")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(metadata["artefact"].(map[string]interface{})["title"]).To(Equal("Gwirfoddoli"))
+		Expect(pageViewsTotal(metadata)).To(Equal(7.0))
+
+		_, metadata = getJSON(server.URL + "/v1/info/volunteer.cy?locale=cy")
+		Expect(metadata["artefact"].(map[string]interface{})["title"]).To(Equal("Gwirfoddoli"))
+
+		_, metadata = getJSON(server.URL + "/v1/info/volunteer.cy?locale=en")
+		Expect(metadata["artefact"].(map[string]interface{})["title"]).To(Equal("Volunteer"))
+	})
+
+	It("responds 404 for a locale with no translation, and 400 for one that isn't a locale", func() {
+		response, metadata := getJSON(server.URL + "/v1/info/volunteer?locale=fr")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(metadata["_response_info"]).To(Equal(map[string]interface{}{"status": "no translation in fr"}))
+
+		response, _ = getJSON(server.URL + "/v1/info/volunteer?locale=Welsh")
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("adds together the statistics for every translation when asked to", func() {
+		_, metadata := getJSON(server.URL + "/v1/info/volunteer")
+		Expect(pageViewsTotal(metadata)).To(Equal(100.0))
+
+		_, metadata = getJSON(server.URL + "/v1/info/volunteer?aggregate_translations=true")
+		Expect(pageViewsTotal(metadata)).To(Equal(107.0))
+
+		_, metadata = getJSON(server.URL + "/v1/info/volunteer?locale=cy&aggregate_translations=true")
+		Expect(pageViewsTotal(metadata)).To(Equal(107.0))
+		Expect(metadata["artefact"].(map[string]interface{})["title"]).To(Equal("Gwirfoddoli"))
+	})
+	It("adds them together in the order of the translations, however quickly they're fetched", func() {
+		performanceAPI.Close()
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Query().Get("filter_by"), "pagePath:")
+			if !strings.HasSuffix(r.URL.Path, "page-statistics") {
+				fmt.Fprintln(w, `{"data":[]}`)
+				return
+			}
+			if path == "/volunteer.cy" {
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			fmt.Fprintf(w, `{"data": [{"pagePath": %q, "values": [
+				{"_start_at": "2014-07-03T00:00:00+00:00", "uniquePageviews:sum": 1}]}]}`, path)
+		})
+
+		item := `{"base_path": "/volunteer", "content_id": "id", "title": "Volunteer", "document_type": "answer",
+			"locale": "en", "links": {"available_translations": [
+				{"content_id": "id", "locale": "en", "title": "Volunteer", "base_path": "/volunteer"},
+				{"content_id": "id", "locale": "cy", "title": "Gwirfoddoli", "base_path": "/volunteer.cy"},
+				{"content_id": "id", "locale": "fr", "title": "Bénévolat", "base_path": "/volunteer.fr"}]}}`
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, pathJSONRequest{"/volunteer": item}, &Config{})
+		server.Close()
+		server = httptest.NewServer(NewRouter(fetcher))
+
+		response, metadata := getJSON(server.URL + "/v1/info/volunteer?aggregate_translations=true")
+		paths := []string{}
+		for _, statistic := range metadata["performance"].(map[string]interface{})["page_views"].([]interface{}) {
+			paths = append(paths, statistic.(map[string]interface{})["path"].(string))
+		}
+		Expect(paths).To(Equal([]string{"/volunteer", "/volunteer.cy", "/volunteer.fr"}))
+
+		again, _ := getJSON(server.URL + "/v1/info/volunteer?aggregate_translations=true")
+		Expect(again.Header.Get("ETag")).To(Equal(response.Header.Get("ETag")))
+	})
+})
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gopkg.in/unrolled/render.v1"
+
+	"github.com/alphagov/metadata-api/analysis"
+	"github.com/alphagov/metadata-api/content"
+	"github.com/alphagov/metadata-api/need_api"
+	"github.com/alphagov/metadata-api/performance_platform"
+)
+
+// Version is a version of the schema of /info responses. V1 is the schema
+// the unversioned /info has always had.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+var versions = []Version{V1, V2}
+
+// mediaType is what clients Accept to ask for this version at the
+// unversioned path.
+func (version Version) mediaType() string {
+	return fmt.Sprintf("application/vnd.govuk.metadata.v%d+json", version)
+}
+
+// prefix is the start of the paths this version is served at.
+func (version Version) prefix() string {
+	return fmt.Sprintf("/v%d", version)
+}
+
+// responseMediaType is the media type of this version's responses. V1 keeps
+// the plain JSON type it has always been served with.
+func (version Version) responseMediaType() string {
+	if version == V1 {
+		return render.ContentJSON
+	}
+	return version.mediaType()
+}
+
+// contentType is the Content-Type header of this version's responses.
+func (version Version) contentType() string {
+	return version.responseMediaType() + "; charset=UTF-8"
+}
+
+// redirectStatus is the status of this version's redirects. V1 keeps 301,
+// which every client follows, and V2 uses 308, which doesn't let clients
+// change the method.
+func (version Version) redirectStatus() int {
+	if version == V1 {
+		return http.StatusMovedPermanently
+	}
+	return http.StatusPermanentRedirect
+}
+
+// body is metadata in this version's schema.
+func (version Version) body(metadata *Metadata) interface{} {
+	if version == V2 {
+		return newMetadataV2(metadata)
+	}
+
+	if artefact, ok := metadata.Artefact.(*content.Artefact); ok {
+		v1 := *metadata
+		v1.Artefact = newArtefactV1(artefact)
+		return &v1
+	}
+	return metadata
+}
+
+// negotiateVersion picks the version of the schema to respond to an
+// unversioned request with, from its Accept header. Without one of the
+// versions' media types, that's V1.
+func negotiateVersion(r *http.Request) Version {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		for _, version := range versions {
+			if mediaType == version.mediaType() {
+				return version
+			}
+		}
+	}
+
+	return V1
+}
+
+// deprecate marks the response to a request to an unversioned path as
+// deprecated, pointing to the same path under version.
+func deprecate(w http.ResponseWriter, r *http.Request, version Version) {
+	header := w.Header()
+	header.Set("Deprecation", "true")
+	header.Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, version.prefix(), r.URL.RequestURI()))
+	header.Add("Vary", "Accept")
+}
+
+// renderVersionError is renderError with the body in version's schema.
+func renderVersionError(w http.ResponseWriter, version Version, status int, errorString string) {
+	if version == V1 {
+		renderError(w, status, errorString)
+		return
+	}
+
+	body, err := json.Marshal(version.body(errorMetadata(errorString)))
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", version.contentType())
+	renderer.Data(w, status, body)
+}
+
+// renderRedirect sends the client to the same endpoint, at prefix, for where
+// the content has moved to, or to the destination itself if it isn't on
+// GOV.UK.
+func renderRedirect(w http.ResponseWriter, r *http.Request, prefix string, version Version,
+	metadataErr *MetadataError) {
+	body, err := json.Marshal(version.body(metadataErr.metadata()))
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", redirectLocation(r, prefix, metadataErr.Location))
+	w.Header().Set("Content-Type", version.contentType())
+	renderer.Data(w, version.redirectStatus(), body)
+}
+
+// redirectLocation is the URL of the endpoint at prefix for destination, if
+// that's a path, with the rest of r's query, or otherwise destination. The
+// destination's own query and fragment are dropped, as they would be when
+// following the redirect.
+func redirectLocation(r *http.Request, prefix, destination string) string {
+	if !strings.HasPrefix(destination, "/") {
+		return destination
+	}
+	if end := strings.IndexAny(destination, "?#"); end >= 0 {
+		destination = destination[:end]
+	}
+
+	location := prefix + (&url.URL{Path: destination}).EscapedPath()
+	query := r.URL.RawQuery
+	if r.URL.Query().Get("url") != "" {
+		values := r.URL.Query()
+		values.Del("url")
+		query = values.Encode()
+	}
+	if query != "" {
+		location += "?" + query
+	}
+	return location
+}
+
+// MetadataV2 is Metadata in the V2 schema. Every key is snake_case, lists
+// are never null, and the response info isn't hidden behind an underscore.
+type MetadataV2 struct {
+	Artefact            interface{}                        `json:"artefact"`
+	Links               map[string][]*content.ExpandedLink `json:"links,omitempty"`
+	Needs               []*need_api.Need                   `json:"needs"`
+	Performance         *PerformanceV2                     `json:"performance"`
+	Anomalies           []analysis.Anomaly                 `json:"anomalies"`
+	EmergingSearchTerms *analysis.EmergingSearchTerms      `json:"emerging_search_terms"`
+	Errors              []*SectionError                    `json:"errors"`
+	ResponseInfo        *ResponseInfo                      `json:"response_info"`
+}
+
+// PerformanceV2 is performance_platform.Statistics with snake_case search
+// terms.
+type PerformanceV2 struct {
+	*performance_platform.Statistics
+	SearchTerms []SearchTermV2 `json:"search_terms"`
+}
+
+type SearchTermV2 struct {
+	Keyword           string                           `json:"keyword"`
+	TotalSearches     int                              `json:"total_searches"`
+	PercentageOfTotal float64                          `json:"percentage_of_total"`
+	Searches          []performance_platform.Statistic `json:"searches"`
+}
+
+func newMetadataV2(metadata *Metadata) *MetadataV2 {
+	v2 := &MetadataV2{
+		Artefact:            metadata.Artefact,
+		Links:               metadata.Links,
+		Needs:               metadata.Needs,
+		Anomalies:           metadata.Anomalies,
+		EmergingSearchTerms: metadata.EmergingSearchTerms,
+		Errors:              metadata.Errors,
+		ResponseInfo:        metadata.ResponseInfo,
+	}
+
+	if v2.Needs == nil {
+		v2.Needs = []*need_api.Need{}
+	}
+	if v2.Anomalies == nil {
+		v2.Anomalies = []analysis.Anomaly{}
+	}
+	if v2.Errors == nil {
+		v2.Errors = []*SectionError{}
+	}
+
+	if metadata.Performance != nil {
+		v2.Performance = &PerformanceV2{
+			Statistics:  metadata.Performance,
+			SearchTerms: make([]SearchTermV2, len(metadata.Performance.SearchTerms)),
+		}
+		for i, term := range metadata.Performance.SearchTerms {
+			v2.Performance.SearchTerms[i] = SearchTermV2{
+				Keyword:           term.Keyword,
+				TotalSearches:     term.TotalSearches,
+				PercentageOfTotal: term.PercentageOfTotal,
+				Searches:          term.Searches,
+			}
+		}
+	}
+
+	return v2
+}
+
+// ArtefactV1 is content.Artefact in the V1 schema, which only has the fields
+// it has always had. The rest are only in V2.
+type ArtefactV1 struct {
+	ID      string   `json:"id"`
+	WebURL  string   `json:"web_url"`
+	Title   string   `json:"title"`
+	Format  string   `json:"format"`
+	Details DetailV1 `json:"details"`
+}
+
+type DetailV1 struct {
+	NeedIDs             []string `json:"need_ids"`
+	BusinessProposition bool     `json:"business_proposition"`
+	Description         string   `json:"description"`
+	Parts               []PartV1 `json:"parts"`
+}
+
+type PartV1 struct {
+	WebURL string `json:"web_url"`
+	Title  string `json:"title"`
+}
+
+func newArtefactV1(artefact *content.Artefact) *ArtefactV1 {
+	v1 := &ArtefactV1{
+		ID:     artefact.ID,
+		WebURL: artefact.WebURL,
+		Title:  artefact.Title,
+		Format: artefact.Format,
+		Details: DetailV1{
+			NeedIDs:             artefact.Details.NeedIDs,
+			BusinessProposition: artefact.Details.BusinessProposition,
+			Description:         artefact.Details.Description,
+		},
+	}
+
+	if artefact.Details.Parts != nil {
+		v1.Details.Parts = make([]PartV1, len(artefact.Details.Parts))
+		for i, part := range artefact.Details.Parts {
+			v1.Details.Parts[i] = PartV1{WebURL: part.WebURL, Title: part.Title}
+		}
+	}
+
+	return v1
+}
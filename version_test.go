@@ -0,0 +1,146 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/alphagov/metadata-api"
+	"github.com/alphagov/metadata-api/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Versions", func() {
+	var server, performanceAPI *httptest.Server
+
+	get := func(path, accept string) (*http.Response, map[string]interface{}) {
+		request, _ := http.NewRequest("GET", server.URL+path, nil)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		Expect(err).To(BeNil())
+		return response, readJSONResponse(response)
+	}
+
+	BeforeEach(func() {
+		performanceAPI = testHandlerServer(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.RawQuery, "searchKeyword") {
+				fmt.Fprintln(w, `{"data": [{"searchKeyword": "Passport", "searchUniques:sum": 4, "values": []}]}`)
+				return
+			}
+			fmt.Fprintln(w, `{"data":[]}`)
+		})
+
+		fetcher := NewFetcher("http://need-api.invalid", performanceAPI.URL, pathJSONRequest{
+			"/one": contentItem("/one"),
+			"/child": `{"base_path": "/child", "content_id": "child", "title": "Child", "document_type": "answer",
+				"links": {"parent": [{"content_id": "id", "title": "Old title", "base_path": "/one"}]}}`,
+		}, &Config{BatchMaxPaths: 3, BatchConcurrency: 3, ContentStoreConcurrency: 2})
+		server = httptest.NewServer(NewRouter(fetcher))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		performanceAPI.Close()
+	})
+
+	It("serves the original schema at /v1", func() {
+		response, v1 := get("/v1/info/one", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		Expect(response.Header.Get("Deprecation")).To(BeEmpty())
+
+		Expect(v1["_response_info"]).To(Equal(map[string]interface{}{"status": "ok"}))
+		Expect(v1).ToNot(HaveKey("errors"))
+		Expect(v1["artefact"]).To(HaveLen(5))
+		for _, key := range []string{"id", "web_url", "title", "format", "details"} {
+			Expect(v1["artefact"]).To(HaveKey(key))
+		}
+		Expect(v1["performance"].(map[string]interface{})["search_terms"]).To(Equal([]interface{}{
+			map[string]interface{}{"Keyword": "passport", "TotalSearches": 4.0, "PercentageOfTotal": 100.0, "Searches": []interface{}{}},
+		}))
+
+		_, unversioned := get("/info/one", "")
+		Expect(unversioned).To(Equal(v1))
+	})
+
+	It("serves the improved schema at /v2", func() {
+		response, v2 := get("/v2/info/one", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/vnd.govuk.metadata.v2+json; charset=UTF-8"))
+
+		Expect(v2["response_info"]).To(Equal(map[string]interface{}{"status": "ok"}))
+		Expect(v2).ToNot(HaveKey("_response_info"))
+		Expect(v2["artefact"]).To(HaveKey("schema_name"))
+		Expect(v2["artefact"]).To(HaveKey("available_translations"))
+		Expect(v2["needs"]).To(Equal([]interface{}{}))
+		Expect(v2["anomalies"]).To(Equal([]interface{}{}))
+		Expect(v2["errors"]).To(Equal([]interface{}{}))
+		Expect(v2["performance"].(map[string]interface{})["search_terms"]).To(Equal([]interface{}{
+			map[string]interface{}{"keyword": "passport", "total_searches": 4.0, "percentage_of_total": 100.0, "searches": []interface{}{}},
+		}))
+
+		definitions := NewOpenAPIDocument().Components.Schemas
+		Expect(definitions.Validate(&schema.Schema{Ref: "#/components/schemas/MetadataV2"}, v2)).To(Succeed())
+	})
+
+	It("serves errors in each version's schema", func() {
+		response, v1 := get("/v1/info/missing", "")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(v1["_response_info"]).To(Equal(map[string]interface{}{"status": "not found"}))
+
+		response, v2 := get("/v2/info/missing", "")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(v2["response_info"]).To(Equal(map[string]interface{}{"status": "not found"}))
+	})
+
+	It("negotiates the version at the unversioned path, which is deprecated", func() {
+		response, v2 := get("/info/one?period=day", "application/json, application/vnd.govuk.metadata.v2+json")
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/vnd.govuk.metadata.v2+json; charset=UTF-8"))
+		Expect(response.Header.Get("Deprecation")).To(Equal("true"))
+		Expect(response.Header.Get("Link")).To(Equal(`</v2/info/one?period=day>; rel="successor-version"`))
+		Expect(response.Header.Get("Vary")).To(Equal("Accept"))
+		Expect(v2).To(HaveKey("response_info"))
+
+		response, v1 := get("/info/one", "application/json")
+		Expect(response.Header.Get("Link")).To(Equal(`</v1/info/one>; rel="successor-version"`))
+		Expect(v1).To(HaveKey("_response_info"))
+	})
+
+	It("serves batches in each version's schema", func() {
+		response, err := http.Post(server.URL+"/v2/info/batch", "application/json", strings.NewReader(`["/one"]`))
+		Expect(err).To(BeNil())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/vnd.govuk.metadata.v2+json; charset=UTF-8"))
+
+		var results map[string]map[string]interface{}
+		body, _ := readResponseBody(response)
+		Expect(json.Unmarshal([]byte(body), &results)).To(Succeed())
+		Expect(results["/one"]["response_info"]).To(Equal(map[string]interface{}{"status": "ok"}))
+	})
+
+	It("expands links when asked to", func() {
+		response, v2 := get("/v2/info/child?expand=parent", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(v2["links"]).To(Equal(map[string]interface{}{
+			"parent": []interface{}{
+				map[string]interface{}{"content_id": "id", "title": "Title", "base_path": "/one"},
+			},
+		}))
+
+		definitions := NewOpenAPIDocument().Components.Schemas
+		Expect(definitions.Validate(&schema.Schema{Ref: "#/components/schemas/MetadataV2"}, v2)).To(Succeed())
+
+		_, v1 := get("/v1/info/child", "")
+		Expect(v1).ToNot(HaveKey("links"))
+
+		response, _ = get("/v1/info/child?expand=people", "")
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})